@@ -2,46 +2,136 @@ package main
 
 import (
 	"client-data-compiler/internal/config"
+	"client-data-compiler/internal/domain/models"
 	"client-data-compiler/internal/handlers"
+	"client-data-compiler/internal/logging"
+	"client-data-compiler/internal/middleware"
+	"client-data-compiler/internal/repository"
 	"client-data-compiler/internal/services"
-	"log"
+	"client-data-compiler/internal/storage"
+	"client-data-compiler/internal/tenant"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		// El logger se configura con la propia configuración
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	slog.SetDefault(logging.New(os.Stderr, level, cfg.Log.Format))
+
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		fatal("Error creando directorio de datos", err)
+	}
+
+	uploadRepo, err := repository.NewFileUploadRepository(filepath.Join(cfg.DataDir, "uploads.json"))
+	if err != nil {
+		fatal("Error cargando registro de uploads", err)
+	}
+
+	savedFilterRepo, err := repository.NewFileSavedFilterRepository(filepath.Join(cfg.DataDir, "saved_filters.json"))
+	if err != nil {
+		fatal("Error cargando filtros guardados", err)
+	}
 
-	if err := os.MkdirAll("uploads", 0755); err != nil {
-		log.Fatal("Error creando directorio uploads:", err)
+	tenants, err := config.LoadTenants(cfg.TenantsFile)
+	if err != nil {
+		fatal("Error cargando la configuración de inquilinos", err)
 	}
-	if err := os.MkdirAll("templates", 0755); err != nil {
-		log.Fatal("Error creando directorio templates:", err)
+	validationRules := make(map[string]models.ValidationRules, len(tenants))
+	storageQuotas := services.StorageQuotas{
+		Default: int64(cfg.Limits.StorageQuota),
+		Tenants: make(map[string]int64, len(tenants)),
+	}
+	for id, settings := range tenants {
+		if err := tenant.Validate(id); err != nil {
+			fatal("Inquilino inválido en "+cfg.TenantsFile, err)
+		}
+		validationRules[id] = settings.Validation
+		storageQuotas.Tenants[id] = int64(settings.StorageQuota)
+	}
+
+	store, err := newStorage(cfg.Storage)
+	if err != nil {
+		fatal("Error configurando el almacenamiento de archivos", err)
 	}
 
 	excelService := services.NewExcelService()
-	validationService := services.NewValidationService()
-	clientService := services.NewClientService(excelService, validationService)
+	validationService := services.NewValidationService(cfg.Workers.Validation, cfg.Workers.ConcurrentThreshold, cfg.Validation, validationRules)
+	clientService := services.NewClientService(excelService, validationService, store)
+	uploadService := services.NewUploadService(clientService, uploadRepo, store, cfg.Workers.Files, storageQuotas, int64(cfg.Limits.MaxUncompressedSize))
+	jobService := services.NewJobService(cfg.JobRetention)
+	savedFilterService := services.NewSavedFilterService(savedFilterRepo)
+	retentionService := services.NewRetentionService(store, uploadRepo, services.RetentionPolicies{
+		Uploads:   cfg.Retention.Uploads,
+		Exports:   cfg.Retention.Exports,
+		Templates: cfg.Retention.Templates,
+	})
+	// backgroundCtx controla las tareas periódicas, que se detienen al apagar
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	retentionService.Start(backgroundCtx, cfg.Retention.Interval)
 
-	clientHandler := handlers.NewClientHandler(clientService)
-	uploadHandler := handlers.NewUploadHandler(clientService)
+	clientHandler := handlers.NewClientHandler(clientService, jobService, savedFilterService, cfg.Timeouts)
+	uploadHandler := handlers.NewUploadHandler(clientService, uploadService, jobService, cfg.Timeouts, cfg.Limits)
+	datasetHandler := handlers.NewDatasetHandler(clientService)
+	jobHandler := handlers.NewJobHandler(jobService)
+	savedFilterHandler := handlers.NewSavedFilterHandler(savedFilterService)
+	authHandler := handlers.NewAuthHandler()
+	retentionHandler := handlers.NewRetentionHandler(retentionService)
+	configHandler := handlers.NewConfigHandler(cfg)
+
+	authenticators, err := middleware.NewAuthenticators(cfg.Auth)
+	if err != nil {
+		fatal("Error configurando la autenticación", err)
+	}
+	if len(authenticators) == 0 {
+		// La configuración solo lo admite con auth.disabled fuera de producción
+		slog.Warn("Autenticación deshabilitada: la API es accesible sin credenciales con permisos de solo lectura")
+	}
 
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := gin.Default()
+	// El registro de accesos sustituye al logger de gin e incluye el ID de
+	// la petición
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.Use(middleware.AccessLog())
+	router.Use(gin.Recovery())
+
+	// Sin proxies de confianza, gin ignora X-Forwarded-For y la IP del
+	// cliente, que limita los intentos de autenticación, no puede falsificarse
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		fatal("Error configurando los proxies de confianza", err)
+	}
 
 	// Configurar CORS
 	router.Use(cors.New(cors.Config{
-		AllowOrigins: []string{
-			"http://localhost:3000",
-			"http://127.0.0.1:3000",
-			"http://localhost:5173",
-		},
+		AllowOrigins: cfg.CORS.AllowedOrigins,
 		AllowMethods: []string{
 			"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS",
 		},
@@ -49,18 +139,16 @@ func main() {
 			"Origin", "Content-Type", "Content-Length",
 			"Accept-Encoding", "X-CSRF-Token", "Authorization",
 			"accept", "origin", "Cache-Control", "X-Requested-With",
+			"X-API-Key", middleware.RequestIDHeader,
 		},
+		ExposeHeaders:    []string{middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           86400,
 	}))
 
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
-
-	router.MaxMultipartMemory = 32 << 20 // 32 MiB
+	router.MaxMultipartMemory = int64(cfg.Limits.MultipartMemory)
 
 	router.GET("/health", func(c *gin.Context) {
-		log.Printf("✅ Health check desde: %s", c.Request.Header.Get("Origin"))
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "ok",
 			"message": "Server is running",
@@ -69,48 +157,236 @@ func main() {
 	})
 
 	router.GET("/test", func(c *gin.Context) {
-		log.Printf("🧪 Test endpoint accessed")
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Backend is working correctly",
 			"cors":    "enabled",
 		})
 	})
 
+	// Las peticiones con credenciales inválidas se limitan por IP antes de
+	// autenticar, porque aún no hay principal al que atribuirlas
 	api := router.Group("/api")
+	api.Use(middleware.NewRateLimiter(cfg.Limits.AuthFailuresPerMinute, cfg.Limits.AuthFailureBurst).FailureMiddleware())
+	if len(authenticators) > 0 {
+		api.Use(middleware.Authenticate(authenticators...))
+	} else {
+		api.Use(middleware.Anonymous(models.RoleViewer))
+	}
+	api.Use(middleware.NewRateLimiter(cfg.Limits.RequestsPerMinute, cfg.Limits.RequestBurst).Middleware())
+
+	// Permisos: todo principal puede consultar y exportar (viewer); subir y
+	// editar requiere editor, y vaciar o eliminar datos, admin
+	uploadLimiter := middleware.NewUploadLimiter(cfg.Limits.ConcurrentUploads)
+	guards := routeGuards{
+		editor: middleware.RequireRole(models.RoleEditor),
+		admin:  middleware.RequireRole(models.RoleAdmin),
+		// El cuerpo admite el tamaño de los archivos más 1 MB para el resto
+		// del formulario
+		upload: []gin.HandlerFunc{
+			uploadLimiter.Middleware(),
+			middleware.LimitRequestBody(int64(cfg.Limits.MaxUploadSize) + 1<<20),
+		},
+		uploadMultiple: []gin.HandlerFunc{
+			uploadLimiter.Middleware(),
+			middleware.LimitRequestBody(int64(cfg.Limits.MaxUploadSize)*int64(cfg.Limits.MaxUploadFiles) + 1<<20),
+		},
+	}
+	editor, admin := guards.editor, guards.admin
 	{
+		// Principal autenticado
+		api.GET("/auth/me", authHandler.Me)
+
 		// Upload de archivos
-		api.POST("/upload", uploadHandler.UploadExcel)
-		api.POST("/upload/multiple", uploadHandler.UploadMultiple)
 		api.GET("/upload/template", uploadHandler.DownloadTemplate)
 		api.GET("/upload/files", uploadHandler.GetUploadedFiles)
-		api.DELETE("/upload/files/:filename", uploadHandler.DeleteUploadedFile)
-
-		// Gestión de clientes
-		api.GET("/clients", clientHandler.GetClients)
-		api.GET("/clients/search", clientHandler.SearchClients)
-		api.GET("/clients/:id", clientHandler.GetClientByID)
-		api.PUT("/clients/:id", clientHandler.UpdateClient)
-		api.DELETE("/clients/:id", clientHandler.DeleteClient)
-		api.DELETE("/clients", clientHandler.ClearAll)
-
-		// Validaciones
-		api.GET("/validate", clientHandler.ValidateAll)
+		api.DELETE("/upload/files/:filename", admin, uploadHandler.DeleteUploadedFile)
+		api.GET("/uploads", uploadHandler.ListUploads)
+		api.GET("/uploads/:id", uploadHandler.GetUpload)
+		api.GET("/uploads/:id/events", jobHandler.StreamUploadEvents)
+
+		// Configuración efectiva, sin secretos
+		api.GET("/admin/config", admin, configHandler.GetConfig)
+
+		// Simulacro de la retención de archivos
+		api.GET("/retention/report", admin, retentionHandler.Report)
+
+		// Descarga de archivos subidos y exportados (solo los del inquilino)
+		api.GET("/files/:id", uploadHandler.DownloadFile)
+		api.GET("/exports/:id", clientHandler.DownloadExport)
+
+		// Trabajos en segundo plano
+		api.GET("/jobs", jobHandler.ListJobs)
+		api.GET("/jobs/:id", jobHandler.GetJob)
+		api.GET("/jobs/:id/events", jobHandler.StreamJobEvents)
+		api.POST("/jobs/:id/cancel", editor, jobHandler.CancelJob)
+
+		// Validación de un cliente individual (no depende de un dataset)
 		api.POST("/validate/single", clientHandler.ValidateSingle)
 
-		// Exportar y estadísticas
-		api.GET("/export", clientHandler.ExportExcel)
-		api.GET("/stats", clientHandler.GetStats)
+		// Filtros guardados (comunes a todos los datasets)
+		api.GET("/filters", savedFilterHandler.ListFilters)
+		api.POST("/filters", editor, savedFilterHandler.CreateFilter)
+		api.GET("/filters/:name", savedFilterHandler.GetFilter)
+		api.PUT("/filters/:name", editor, savedFilterHandler.UpdateFilter)
+		api.DELETE("/filters/:name", editor, savedFilterHandler.DeleteFilter)
+
+		// Gestión de datasets
+		api.GET("/datasets", datasetHandler.ListDatasets)
+		api.GET("/datasets/:dataset", datasetHandler.GetDataset)
+		api.PATCH("/datasets/:dataset", editor, datasetHandler.RenameDataset)
+		api.DELETE("/datasets/:dataset", admin, datasetHandler.DeleteDataset)
+
+		// Rutas sobre el dataset por defecto (o el indicado con ?dataset=)
+		registerDatasetRoutes(api, clientHandler, uploadHandler, guards)
+
+		// Rutas sobre un dataset específico
+		registerDatasetRoutes(api.Group("/datasets/:dataset"), clientHandler, uploadHandler, guards)
+	}
+
+	// Las peticiones heredan requestCtx, que se cancela si al apagar no
+	// terminan en el plazo configurado
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	server := &http.Server{
+		Addr:        ":" + cfg.Port,
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return requestCtx },
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	slog.Info("Servidor iniciado",
+		"port", cfg.Port,
+		"environment", cfg.Environment,
+		"cors_origins", strings.Join(cfg.CORS.AllowedOrigins, ","),
+		"log_level", cfg.Log.Level)
+
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-serverErr:
+		fatal("Error del servidor HTTP", err)
+	case <-signalCtx.Done():
+	}
+	// Una segunda señal termina el proceso de inmediato
+	stopSignals()
+
+	slog.Info("Apagando el servidor", "timeout", cfg.ShutdownTimeout.String())
+	stopBackground()
+	shutdown(server, cancelRequests, jobService, cfg.ShutdownTimeout)
+
+	for _, repo := range []io.Closer{uploadRepo, savedFilterRepo} {
+		if err := repo.Close(); err != nil {
+			slog.Error("Error guardando los registros", "error", err)
+		}
 	}
 
-	// Servir archivos estáticos
-	router.Static("/files", "./uploads")
+	slog.Info("Servidor apagado")
+}
+
+// fatal registra el error y termina el proceso
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// shutdownGrace tiempo que se espera a que las peticiones canceladas al
+// vencer el plazo de apagado terminen y eliminen sus archivos parciales
+const shutdownGrace = 10 * time.Second
 
-	log.Printf("🚀 Servidor iniciado en puerto %s", cfg.Port)
-	log.Printf("🌐 CORS configurado para: http://localhost:3000")
-	log.Printf("📍 Health check: http://localhost:%s/health", cfg.Port)
-	log.Printf("📋 API base: http://localhost:%s/api", cfg.Port)
-	log.Printf("🧪 Test: http://localhost:%s/test", cfg.Port)
-	log.Printf("👥 Clientes: http://localhost:%s/api/clients", cfg.Port)
+// shutdown deja de aceptar conexiones y espera como máximo timeout a que
+// terminen las peticiones y los trabajos en curso. Al vencer el plazo cancela
+// los que queden y les da shutdownGrace para limpiar antes de cerrar las
+// conexiones.
+func shutdown(server *http.Server, cancelRequests context.CancelFunc, jobService services.JobService, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("Peticiones en curso al vencer el plazo de apagado, se cancelan", "timeout", timeout.String())
+		cancelRequests()
+	}
+
+	if err := jobService.Shutdown(ctx); err != nil {
+		slog.Warn("Trabajos en curso al vencer el plazo de apagado, cancelados", "timeout", timeout.String())
+	}
+
+	if ctx.Err() == nil {
+		return
+	}
+
+	cancelRequests()
+	graceCtx, cancelGrace := context.WithTimeout(context.Background(), shutdownGrace)
+	defer cancelGrace()
+	if err := server.Shutdown(graceCtx); err != nil {
+		slog.Warn("Conexiones abiertas después de cancelar las peticiones, se cierran", "grace", shutdownGrace.String())
+		server.Close()
+	}
+}
+
+// newStorage crea el almacén de archivos del driver configurado
+func newStorage(cfg config.Storage) (storage.Storage, error) {
+	switch cfg.Driver {
+	case "local":
+		slog.Info("Almacenamiento local", "root", cfg.LocalRoot)
+		return storage.NewLocal(cfg.LocalRoot), nil
+	case "s3":
+		slog.Info("Almacenamiento S3", "bucket", cfg.S3.Bucket)
+		return storage.NewS3(storage.S3Options{
+			Endpoint:        cfg.S3.Endpoint,
+			Region:          cfg.S3.Region,
+			Bucket:          cfg.S3.Bucket,
+			Prefix:          cfg.S3.Prefix,
+			AccessKeyID:     cfg.S3.AccessKeyID,
+			SecretAccessKey: cfg.S3.SecretAccessKey,
+			PathStyle:       cfg.S3.PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("driver de almacenamiento desconocido %q (use local o s3)", cfg.Driver)
+	}
+}
+
+// routeGuards middleware que protegen rutas concretas
+type routeGuards struct {
+	// editor y admin exigen los roles correspondientes
+	editor gin.HandlerFunc
+	admin  gin.HandlerFunc
+	// upload y uploadMultiple limitan las subidas simultáneas y el tamaño de
+	// la petición al subir uno o varios archivos
+	upload         []gin.HandlerFunc
+	uploadMultiple []gin.HandlerFunc
+}
+
+// registerDatasetRoutes registra las rutas que operan sobre los clientes de un
+// dataset
+func registerDatasetRoutes(rg *gin.RouterGroup, clientHandler *handlers.ClientHandler, uploadHandler *handlers.UploadHandler, guards routeGuards) {
+	editor, admin := guards.editor, guards.admin
+
+	// Upload de archivos
+	rg.POST("/upload", chain(editor, guards.upload, uploadHandler.UploadExcel)...)
+	rg.POST("/upload/multiple", chain(editor, guards.uploadMultiple, uploadHandler.UploadMultiple)...)
+
+	// Gestión de clientes
+	rg.GET("/clients", clientHandler.GetClients)
+	rg.GET("/clients/search", clientHandler.SearchClients)
+	rg.GET("/clients/lookup", clientHandler.LookupClients)
+	rg.POST("/clients/bulk-delete", admin, clientHandler.BulkDelete)
+	rg.GET("/clients/:id", clientHandler.GetClientByID)
+	rg.PUT("/clients/:id", editor, clientHandler.UpdateClient)
+	rg.DELETE("/clients/:id", editor, clientHandler.DeleteClient)
+	rg.DELETE("/clients", admin, clientHandler.ClearAll)
+
+	// Validaciones (revalidar modifica el estado de los clientes)
+	rg.GET("/validate", editor, clientHandler.ValidateAll)
+
+	// Exportar y estadísticas
+	rg.GET("/export", clientHandler.ExportExcel)
+	rg.GET("/stats", clientHandler.GetStats)
+}
 
-	log.Fatal(router.Run(":" + cfg.Port))
+// chain encadena el control de rol, los middleware de la ruta y el handler
+func chain(role gin.HandlerFunc, routeMiddleware []gin.HandlerFunc, handler gin.HandlerFunc) []gin.HandlerFunc {
+	chained := append([]gin.HandlerFunc{role}, routeMiddleware...)
+	return append(chained, handler)
 }
@@ -1,27 +1,297 @@
 package config
 
 import (
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/utils"
+	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"time"
 )
 
+// Config configuración de la API. Load la combina, de menor a mayor
+// prioridad, de los valores por defecto, un archivo YAML, las variables de
+// entorno y los flags de la línea de comandos.
 type Config struct {
-	Port        string
-	Environment string
+	Port string `yaml:"port"`
+	// Environment development, staging o production
+	Environment string `yaml:"environment"`
+	DataDir     string `yaml:"data_dir"`
+	// JobRetention tiempo que se conservan los trabajos terminados y su resultado
+	JobRetention time.Duration `yaml:"job_retention"`
+	// ShutdownTimeout tiempo que se espera al apagar a que terminen las
+	// peticiones y los trabajos en curso antes de cancelarlos
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	Log             Log           `yaml:"log"`
+	CORS            CORS          `yaml:"cors"`
+	// TrustedProxies IPs o rangos CIDR de los proxies cuyas cabeceras
+	// X-Forwarded-For y X-Real-IP se aceptan para obtener la IP del cliente;
+	// sin proxies se usa la dirección de la conexión
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	Timeouts       Timeouts `yaml:"timeouts"`
+	Workers        Workers  `yaml:"workers"`
+	Auth           Auth     `yaml:"auth"`
+	Limits         Limits   `yaml:"limits"`
+	// Validation reglas de validación de los inquilinos sin reglas propias
+	Validation models.ValidationRules `yaml:"validation"`
+	// TenantsFile archivo JSON opcional con la configuración de cada
+	// inquilino; DATA_DIR/tenants.json si no se indica
+	TenantsFile string    `yaml:"tenants_file"`
+	Storage     Storage   `yaml:"storage"`
+	Retention   Retention `yaml:"retention"`
 }
 
-func Load() *Config {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+// Log nivel y formato de los registros
+type Log struct {
+	// Level debug, info, warn o error; en debug se registra cada fila leída
+	Level string `yaml:"level"`
+	// Format text o json
+	Format string `yaml:"format"`
+}
+
+// CORS orígenes desde los que los navegadores pueden llamar a la API
+type CORS struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// Storage almacén de los archivos subidos, exportados y las plantillas
+type Storage struct {
+	// Driver "local" (por defecto) o "s3"
+	Driver string `yaml:"driver"`
+	// LocalRoot directorio del almacenamiento local, que contiene uploads/ y
+	// templates/
+	LocalRoot string    `yaml:"local_root"`
+	S3        S3Storage `yaml:"s3"`
+}
+
+// S3Storage bucket compatible con S3 del driver "s3"
+type S3Storage struct {
+	// Endpoint URL del servicio; vacío para AWS
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	Prefix          string `yaml:"prefix"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	// PathStyle direcciona el bucket en la ruta; necesario para MinIO y la
+	// mayoría de los servicios compatibles
+	PathStyle bool `yaml:"path_style"`
+}
+
+// Retention plazos de conservación de los archivos almacenados desde su
+// último uso. Un plazo cero los conserva indefinidamente.
+type Retention struct {
+	// Interval frecuencia con la que se eliminan los archivos vencidos
+	Interval time.Duration `yaml:"interval"`
+	Uploads  time.Duration `yaml:"uploads"`
+	Exports  time.Duration `yaml:"exports"`
+	// Templates solo se aplica a la plantilla común generada; las plantillas
+	// propias de los inquilinos se conservan siempre
+	Templates time.Duration `yaml:"templates"`
+}
+
+// TenantSettings configuración propia de un inquilino
+type TenantSettings struct {
+	Validation models.ValidationRules `json:"validation"`
+	// StorageQuota espacio máximo de sus archivos; si es cero se usa
+	// Limits.StorageQuota
+	StorageQuota ByteSize `json:"storage_quota,omitempty"`
+}
+
+// Limits límites de uso de la API para proteger el disco y la CPU
+type Limits struct {
+	// RequestsPerMinute y RequestBurst definen el límite de peticiones de
+	// cada principal (o IP si la petición es anónima)
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	RequestBurst      int `yaml:"request_burst"`
+	// AuthFailuresPerMinute y AuthFailureBurst limitan las peticiones
+	// rechazadas por credenciales inválidas de cada IP
+	AuthFailuresPerMinute int `yaml:"auth_failures_per_minute"`
+	AuthFailureBurst      int `yaml:"auth_failure_burst"`
+	// ConcurrentUploads subidas simultáneas de cada principal, incluido su
+	// procesamiento en segundo plano
+	ConcurrentUploads int `yaml:"concurrent_uploads"`
+	// MaxUploadSize tamaño máximo de cada archivo subido
+	MaxUploadSize ByteSize `yaml:"max_upload_size"`
+	// MaxUploadFiles archivos máximos de una subida múltiple
+	MaxUploadFiles int `yaml:"max_upload_files"`
+	// MaxUncompressedSize tamaño máximo de un archivo subido una vez
+	// descomprimido, como protección contra bombas zip
+	MaxUncompressedSize ByteSize `yaml:"max_uncompressed_size"`
+	// MultipartMemory parte de un formulario que se mantiene en memoria; el
+	// resto se escribe en archivos temporales
+	MultipartMemory ByteSize `yaml:"multipart_memory"`
+	// StorageQuota espacio máximo de los archivos de cada inquilino
+	StorageQuota ByteSize `yaml:"storage_quota"`
+}
+
+// Auth credenciales aceptadas por la API. Se requiere al menos una API key o
+// una clave JWT, salvo que Disabled lo desactive explícitamente.
+type Auth struct {
+	// Disabled permite arrancar sin credenciales fuera de producción. Las
+	// peticiones se atienden entonces como un principal anónimo de solo lectura.
+	Disabled bool     `yaml:"disabled"`
+	APIKeys  []APIKey `yaml:"api_keys"`
+	// JWTSecret secreto compartido para verificar tokens HS256
+	JWTSecret string `yaml:"jwt_secret"`
+	// JWTPublicKeyFile archivo PEM con la clave pública para tokens RS256
+	JWTPublicKeyFile string `yaml:"jwt_public_key_file"`
+	// JWTIssuer y JWTAudience, si se indican, deben coincidir con iss y aud
+	JWTIssuer   string `yaml:"jwt_issuer"`
+	JWTAudience string `yaml:"jwt_audience"`
+}
+
+// APIKey API key estática y el principal que identifica
+type APIKey struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+	// Role rol del principal; viewer si no se indica
+	Role string `yaml:"role,omitempty"`
+	// Tenant inquilino del principal; el inquilino por defecto si no se indica
+	Tenant string `yaml:"tenant,omitempty"`
+}
+
+// Enabled indica si hay alguna credencial configurada
+func (a Auth) Enabled() bool {
+	return len(a.APIKeys) > 0 || a.JWTSecret != "" || a.JWTPublicKeyFile != ""
+}
+
+// Workers ajustes de concurrencia del procesamiento de archivos
+type Workers struct {
+	// Validation goroutines que validan los clientes de un bloque
+	Validation int `yaml:"validation"`
+	// ConcurrentThreshold mínimo de clientes de un bloque para validarlo en paralelo
+	ConcurrentThreshold int `yaml:"concurrent_threshold"`
+	// Files archivos de una subida múltiple que se leen en paralelo
+	Files int `yaml:"files"`
+}
+
+// Timeouts plazos máximos de las operaciones de larga duración. Al vencer, la
+// operación se cancela y falla con OPERATION_TIMEOUT.
+type Timeouts struct {
+	Import     time.Duration `yaml:"import"`
+	Validation time.Duration `yaml:"validation"`
+	Export     time.Duration `yaml:"export"`
+}
+
+// defaults obtiene la configuración por defecto
+func defaults() *Config {
+	return &Config{
+		Port:            "8080",
+		Environment:     "development",
+		DataDir:         "data",
+		JobRetention:    time.Hour,
+		ShutdownTimeout: 30 * time.Second,
+		Log: Log{
+			Level:  "info",
+			Format: "text",
+		},
+		CORS: CORS{
+			AllowedOrigins: []string{
+				"http://localhost:3000",
+				"http://127.0.0.1:3000",
+				"http://localhost:5173",
+			},
+		},
+		Timeouts: Timeouts{
+			Import:     30 * time.Minute,
+			Validation: 10 * time.Minute,
+			Export:     5 * time.Minute,
+		},
+		Workers: Workers{
+			Validation:          runtime.GOMAXPROCS(0),
+			ConcurrentThreshold: 100,
+			Files:               runtime.GOMAXPROCS(0),
+		},
+		Limits: Limits{
+			RequestsPerMinute:     300,
+			RequestBurst:          60,
+			AuthFailuresPerMinute: 10,
+			AuthFailureBurst:      10,
+			ConcurrentUploads:     2,
+			MaxUploadSize:         32 << 20,
+			MaxUploadFiles:        20,
+			MaxUncompressedSize:   512 << 20,
+			MultipartMemory:       32 << 20,
+			StorageQuota:          1 << 30,
+		},
+		Validation: models.ValidationRules{
+			Region:         utils.DefaultRegion,
+			PhoneAreaCodes: utils.DefaultPhoneAreaCodes,
+			EmailDomains:   utils.DefaultEmailDomains,
+		},
+		Storage: Storage{
+			Driver:    "local",
+			LocalRoot: ".",
+			S3: S3Storage{
+				Region: "us-east-1",
+			},
+		},
+		Retention: Retention{
+			Interval:  time.Hour,
+			Uploads:   30 * 24 * time.Hour,
+			Exports:   24 * time.Hour,
+			Templates: 0,
+		},
 	}
+}
+
+// Load obtiene la configuración de los valores por defecto, el archivo YAML
+// indicado con -config o CONFIG_FILE (config.yaml si existe y no se indica
+// otro), las variables de entorno y los flags de args, cada fuente con
+// prioridad sobre las anteriores. Devuelve un error si algún valor no se
+// puede interpretar o la configuración resultante no es válida.
+func Load(args []string) (*Config, error) {
+	cfg := defaults()
 
-	env := os.Getenv("ENVIRONMENT")
-	if env == "" {
-		env = "development"
+	configFile, applyFlags, err := parseFlags(cfg, args)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Config{
-		Port:        port,
-		Environment: env,
+	if err := cfg.loadFile(configFile); err != nil {
+		return nil, err
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := applyFlags(); err != nil {
+		return nil, err
+	}
+
+	if cfg.TenantsFile == "" {
+		cfg.TenantsFile = filepath.Join(cfg.DataDir, "tenants.json")
 	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// LoadTenants lee la configuración de los inquilinos, un objeto JSON indexado
+// por inquilino, p. ej.
+//
+//	{"tapachula": {"validation": {"region": "Tapachula", "phone_area_codes": ["962"]}}}
+//
+// Si el archivo no existe no hay configuración específica y todos los
+// inquilinos usan las reglas por defecto.
+func LoadTenants(path string) (map[string]TenantSettings, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]TenantSettings{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer %s: %w", path, err)
+	}
+
+	var tenants map[string]TenantSettings
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("configuración de inquilinos inválida en %s: %w", path, err)
+	}
+
+	return tenants, nil
 }
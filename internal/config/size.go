@@ -0,0 +1,105 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ByteSize tamaño en bytes. En JSON, YAML y variables de entorno acepta un número
+// de bytes o un número con unidad: KB, MB, GB o TB (múltiplos de 1024).
+type ByteSize int64
+
+// byteUnits multiplicadores de las unidades aceptadas, de la más larga a la
+// más corta para que "MB" no se interprete como "B"
+var byteUnits = []struct {
+	suffix string
+	size   ByteSize
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseByteSize interpreta un tamaño como "512KB", "32MB" o "1048576"
+func ParseByteSize(value string) (ByteSize, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+
+	multiplier := ByteSize(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			multiplier = unit.size
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("tamaño inválido '%s'", value)
+	}
+
+	return ByteSize(n * float64(multiplier)), nil
+}
+
+// String devuelve el tamaño con la mayor unidad exacta, p. ej. "32MB"
+func (b ByteSize) String() string {
+	for _, unit := range byteUnits {
+		if b != 0 && b%unit.size == 0 {
+			return fmt.Sprintf("%d%s", b/unit.size, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", b)
+}
+
+// UnmarshalJSON acepta un número de bytes o una cadena con unidad
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	var n int64
+	if err := json.Unmarshal(data, &n); err == nil {
+		*b = ByteSize(n)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("tamaño inválido %s", data)
+	}
+
+	parsed, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+// MarshalJSON representa el tamaño con su unidad
+func (b ByteSize) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+// UnmarshalYAML acepta un número de bytes o una cadena con unidad
+func (b *ByteSize) UnmarshalYAML(value *yaml.Node) error {
+	var n int64
+	if err := value.Decode(&n); err == nil {
+		*b = ByteSize(n)
+		return nil
+	}
+
+	parsed, err := ParseByteSize(value.Value)
+	if err != nil {
+		return err
+	}
+	*b = parsed
+	return nil
+}
+
+// MarshalYAML representa el tamaño con su unidad
+func (b ByteSize) MarshalYAML() (interface{}, error) {
+	return b.String(), nil
+}
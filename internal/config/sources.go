@@ -0,0 +1,311 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile archivo de configuración que se lee si existe y no se
+// indica otro
+const defaultConfigFile = "config.yaml"
+
+// setting ajuste configurable por variable de entorno y, salvo los secretos,
+// por flag
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	value flag.Value
+}
+
+// settings obtiene los ajustes configurables de c, con sus valores enlazados a
+// los campos correspondientes
+func (c *Config) settings() []setting {
+	return []setting{
+		{"PORT", "port", "puerto HTTP", (*stringValue)(&c.Port)},
+		{"ENVIRONMENT", "environment", "entorno: development, staging o production", (*stringValue)(&c.Environment)},
+		{"DATA_DIR", "data-dir", "directorio de datos", (*stringValue)(&c.DataDir)},
+		{"JOB_RETENTION", "job-retention", "tiempo que se conservan los trabajos terminados", (*durationValue)(&c.JobRetention)},
+		{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "espera máxima del apagado a las peticiones y trabajos en curso", (*durationValue)(&c.ShutdownTimeout)},
+		{"LOG_LEVEL", "log-level", "nivel de log: debug, info, warn o error", (*stringValue)(&c.Log.Level)},
+		{"LOG_FORMAT", "log-format", "formato de log: text o json", (*stringValue)(&c.Log.Format)},
+		{"CORS_ALLOWED_ORIGINS", "cors-allowed-origins", "orígenes CORS permitidos, separados por comas", (*listValue)(&c.CORS.AllowedOrigins)},
+		{"TRUSTED_PROXIES", "trusted-proxies", "IPs o rangos CIDR de los proxies de confianza, separados por comas", (*listValue)(&c.TrustedProxies)},
+
+		{"IMPORT_TIMEOUT", "import-timeout", "plazo máximo de una importación", (*durationValue)(&c.Timeouts.Import)},
+		{"VALIDATION_TIMEOUT", "validation-timeout", "plazo máximo de una validación", (*durationValue)(&c.Timeouts.Validation)},
+		{"EXPORT_TIMEOUT", "export-timeout", "plazo máximo de una exportación", (*durationValue)(&c.Timeouts.Export)},
+
+		{"VALIDATION_WORKERS", "validation-workers", "goroutines de validación", (*intValue)(&c.Workers.Validation)},
+		{"VALIDATION_CONCURRENT_THRESHOLD", "validation-concurrent-threshold", "mínimo de clientes para validar en paralelo", (*intValue)(&c.Workers.ConcurrentThreshold)},
+		{"UPLOAD_FILE_WORKERS", "upload-file-workers", "archivos de una subida múltiple leídos en paralelo", (*intValue)(&c.Workers.Files)},
+
+		{"AUTH_DISABLED", "auth-disabled", "permitir el acceso anónimo de solo lectura sin credenciales (no en producción)", (*boolValue)(&c.Auth.Disabled)},
+		{"API_KEYS", "", "", (*apiKeysValue)(&c.Auth.APIKeys)},
+		{"JWT_HS256_SECRET", "", "", (*stringValue)(&c.Auth.JWTSecret)},
+		{"JWT_RS256_PUBLIC_KEY_FILE", "jwt-public-key-file", "archivo PEM con la clave pública RS256", (*stringValue)(&c.Auth.JWTPublicKeyFile)},
+		{"JWT_ISSUER", "jwt-issuer", "emisor (iss) requerido en los tokens", (*stringValue)(&c.Auth.JWTIssuer)},
+		{"JWT_AUDIENCE", "jwt-audience", "audiencia (aud) requerida en los tokens", (*stringValue)(&c.Auth.JWTAudience)},
+
+		{"RATE_LIMIT_PER_MINUTE", "rate-limit-per-minute", "peticiones por minuto de cada principal", (*intValue)(&c.Limits.RequestsPerMinute)},
+		{"RATE_LIMIT_BURST", "rate-limit-burst", "ráfaga de peticiones de cada principal", (*intValue)(&c.Limits.RequestBurst)},
+		{"AUTH_FAILURE_LIMIT_PER_MINUTE", "auth-failure-limit-per-minute", "peticiones con credenciales inválidas por minuto de cada IP", (*intValue)(&c.Limits.AuthFailuresPerMinute)},
+		{"AUTH_FAILURE_BURST", "auth-failure-burst", "ráfaga de peticiones con credenciales inválidas de cada IP", (*intValue)(&c.Limits.AuthFailureBurst)},
+		{"MAX_CONCURRENT_UPLOADS", "max-concurrent-uploads", "subidas simultáneas de cada principal", (*intValue)(&c.Limits.ConcurrentUploads)},
+		{"MAX_UPLOAD_SIZE", "max-upload-size", "tamaño máximo de cada archivo subido", (*sizeValue)(&c.Limits.MaxUploadSize)},
+		{"MAX_UPLOAD_FILES", "max-upload-files", "archivos máximos de una subida múltiple", (*intValue)(&c.Limits.MaxUploadFiles)},
+		{"MAX_XLSX_UNCOMPRESSED_SIZE", "max-xlsx-uncompressed-size", "tamaño máximo de un archivo descomprimido", (*sizeValue)(&c.Limits.MaxUncompressedSize)},
+		{"MULTIPART_MEMORY", "multipart-memory", "parte de un formulario que se mantiene en memoria", (*sizeValue)(&c.Limits.MultipartMemory)},
+		{"TENANT_STORAGE_QUOTA", "tenant-storage-quota", "espacio máximo de los archivos de cada inquilino", (*sizeValue)(&c.Limits.StorageQuota)},
+
+		{"VALIDATION_REGION", "validation-region", "región de las ladas permitidas", (*stringValue)(&c.Validation.Region)},
+		{"VALIDATION_PHONE_AREA_CODES", "validation-phone-area-codes", "ladas permitidas, separadas por comas", (*listValue)(&c.Validation.PhoneAreaCodes)},
+		{"VALIDATION_EMAIL_DOMAINS", "validation-email-domains", "dominios de correo permitidos, separados por comas", (*listValue)(&c.Validation.EmailDomains)},
+		{"TENANTS_FILE", "tenants-file", "archivo JSON con la configuración de cada inquilino", (*stringValue)(&c.TenantsFile)},
+
+		{"STORAGE_DRIVER", "storage-driver", "almacenamiento: local o s3", (*stringValue)(&c.Storage.Driver)},
+		{"STORAGE_LOCAL_ROOT", "storage-local-root", "directorio del almacenamiento local", (*stringValue)(&c.Storage.LocalRoot)},
+		{"S3_ENDPOINT", "s3-endpoint", "URL del servicio S3; vacío para AWS", (*stringValue)(&c.Storage.S3.Endpoint)},
+		{"S3_REGION", "s3-region", "región de S3", (*stringValue)(&c.Storage.S3.Region)},
+		{"S3_BUCKET", "s3-bucket", "bucket de S3", (*stringValue)(&c.Storage.S3.Bucket)},
+		{"S3_PREFIX", "s3-prefix", "prefijo de las claves en el bucket", (*stringValue)(&c.Storage.S3.Prefix)},
+		{"S3_ACCESS_KEY_ID", "", "", (*stringValue)(&c.Storage.S3.AccessKeyID)},
+		{"S3_SECRET_ACCESS_KEY", "", "", (*stringValue)(&c.Storage.S3.SecretAccessKey)},
+		{"S3_FORCE_PATH_STYLE", "s3-path-style", "direccionar el bucket en la ruta", (*boolValue)(&c.Storage.S3.PathStyle)},
+
+		{"RETENTION_INTERVAL", "retention-interval", "frecuencia de la limpieza de archivos vencidos", (*durationValue)(&c.Retention.Interval)},
+		{"RETENTION_UPLOADS", "retention-uploads", "retención de los archivos subidos; 0 los conserva", (*durationValue)(&c.Retention.Uploads)},
+		{"RETENTION_EXPORTS", "retention-exports", "retención de los archivos exportados; 0 los conserva", (*durationValue)(&c.Retention.Exports)},
+		{"RETENTION_TEMPLATES", "retention-templates", "retención de la plantilla común generada; 0 la conserva", (*durationValue)(&c.Retention.Templates)},
+	}
+}
+
+// parseFlags interpreta los flags de args. Devuelve el archivo de
+// configuración indicado con -config y una función que aplica a c el resto de
+// los flags, para que tengan prioridad sobre el archivo y el entorno.
+func parseFlags(c *Config, args []string) (string, func() error, error) {
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	configFile := fs.String("config", "", "archivo de configuración YAML (CONFIG_FILE)")
+
+	var pending []*pendingFlag
+	for _, s := range c.settings() {
+		if s.flag == "" {
+			continue
+		}
+		p := &pendingFlag{name: s.flag, target: s.value}
+		pending = append(pending, p)
+		fs.Var(p, s.flag, fmt.Sprintf("%s (%s)", s.usage, s.env))
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return "", nil, err
+	}
+	if fs.NArg() > 0 {
+		return "", nil, fmt.Errorf("argumentos inesperados: %s", strings.Join(fs.Args(), " "))
+	}
+
+	apply := func() error {
+		for _, p := range pending {
+			for _, raw := range p.raw {
+				if err := p.target.Set(raw); err != nil {
+					return fmt.Errorf("flag -%s: %w", p.name, err)
+				}
+			}
+		}
+		return nil
+	}
+
+	return *configFile, apply, nil
+}
+
+// loadFile aplica el archivo de configuración YAML indicado, el de
+// CONFIG_FILE o, si no se indica ninguno, config.yaml si existe. Los campos
+// desconocidos son un error para detectar erratas.
+func (c *Config) loadFile(path string) error {
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	optional := path == ""
+	if optional {
+		path = defaultConfigFile
+	}
+
+	file, err := os.Open(path)
+	if optional && errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("no se pudo leer el archivo de configuración: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("archivo de configuración %s inválido: %w", path, err)
+	}
+
+	return nil
+}
+
+// applyEnv aplica las variables de entorno definidas
+func (c *Config) applyEnv() error {
+	for _, s := range c.settings() {
+		value := os.Getenv(s.env)
+		if value == "" {
+			continue
+		}
+		if err := s.value.Set(value); err != nil {
+			return fmt.Errorf("variable de entorno %s: %w", s.env, err)
+		}
+	}
+	return nil
+}
+
+// pendingFlag guarda los valores de un flag para aplicarlos después del
+// archivo y del entorno
+type pendingFlag struct {
+	name   string
+	target flag.Value
+	raw    []string
+}
+
+func (p *pendingFlag) String() string {
+	if p == nil || p.target == nil {
+		return ""
+	}
+	return p.target.String()
+}
+
+func (p *pendingFlag) Set(value string) error {
+	p.raw = append(p.raw, value)
+	return nil
+}
+
+// IsBoolFlag permite usar los flags booleanos sin valor, p. ej. -s3-path-style
+func (p *pendingFlag) IsBoolFlag() bool {
+	b, ok := p.target.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+type stringValue string
+
+func (v *stringValue) String() string     { return string(*v) }
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+
+type intValue int
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("entero inválido '%s'", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+type boolValue bool
+
+func (v *boolValue) String() string   { return strconv.FormatBool(bool(*v)) }
+func (v *boolValue) IsBoolFlag() bool { return true }
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("booleano inválido '%s'", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+type durationValue time.Duration
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("duración inválida '%s'", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+type sizeValue ByteSize
+
+func (v *sizeValue) String() string { return ByteSize(*v).String() }
+
+func (v *sizeValue) Set(s string) error {
+	size, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*v = sizeValue(size)
+	return nil
+}
+
+// listValue lista separada por comas; los elementos vacíos se ignoran
+type listValue []string
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
+
+func (v *listValue) Set(s string) error {
+	list := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*v = list
+	return nil
+}
+
+// apiKeysValue API keys con el formato nombre:clave[:rol[:inquilino]]
+// separadas por comas, p. ej. "ci:abc123:editor,tap:def456:viewer:tapachula"
+type apiKeysValue []APIKey
+
+// String no muestra las claves
+func (v *apiKeysValue) String() string {
+	names := make([]string, len(*v))
+	for i, apiKey := range *v {
+		names[i] = apiKey.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func (v *apiKeysValue) Set(s string) error {
+	keys := []APIKey{}
+	for i, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 4 || parts[0] == "" || parts[1] == "" {
+			// No se incluye la entrada en el error para no exponer la clave
+			return fmt.Errorf("la entrada %d no tiene el formato nombre:clave[:rol[:inquilino]]", i+1)
+		}
+
+		apiKey := APIKey{Name: parts[0], Key: parts[1]}
+		if len(parts) >= 3 {
+			apiKey.Role = parts[2]
+		}
+		if len(parts) == 4 {
+			apiKey.Tenant = parts[3]
+		}
+		keys = append(keys, apiKey)
+	}
+	*v = keys
+	return nil
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/logging"
+	"client-data-compiler/internal/tenant"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted valor con el que se ocultan los secretos
+const redacted = "[REDACTED]"
+
+// placeholderAPIKeys claves de ejemplo que no deben usarse como credenciales
+var placeholderAPIKeys = map[string]bool{
+	"cambiar-esta-clave": true,
+}
+
+// areaCodePattern lada de 3 dígitos
+var areaCodePattern = regexp.MustCompile(`^[0-9]{3}$`)
+
+// Validate comprueba la configuración y devuelve un único error con todos los
+// problemas encontrados
+func (c *Config) Validate() error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		addf("port: '%s' no es un puerto entre 1 y 65535", c.Port)
+	}
+	switch c.Environment {
+	case "development", "staging", "production":
+	default:
+		addf("environment: '%s' no es development, staging ni production", c.Environment)
+	}
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		addf("log.level: %v", err)
+	}
+	if c.Log.Format != logging.FormatText && c.Log.Format != logging.FormatJSON {
+		addf("log.format: '%s' no es text ni json", c.Log.Format)
+	}
+	if c.DataDir == "" {
+		addf("data_dir: no puede estar vacío")
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 {
+		addf("cors.allowed_origins: debe incluir al menos un origen")
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Contains(origin, "*") {
+			addf("cors.allowed_origins: '%s' no es un origen http(s) válido", origin)
+		}
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				addf("trusted_proxies: '%s' no es una IP ni un rango CIDR válido", proxy)
+			}
+		}
+	}
+
+	positiveDurations := map[string]time.Duration{
+		"job_retention":       c.JobRetention,
+		"shutdown_timeout":    c.ShutdownTimeout,
+		"timeouts.import":     c.Timeouts.Import,
+		"timeouts.validation": c.Timeouts.Validation,
+		"timeouts.export":     c.Timeouts.Export,
+		"retention.interval":  c.Retention.Interval,
+	}
+	for name, d := range positiveDurations {
+		if d <= 0 {
+			addf("%s: debe ser una duración positiva", name)
+		}
+	}
+	retentions := map[string]time.Duration{
+		"retention.uploads":   c.Retention.Uploads,
+		"retention.exports":   c.Retention.Exports,
+		"retention.templates": c.Retention.Templates,
+	}
+	for name, d := range retentions {
+		if d < 0 {
+			addf("%s: no puede ser negativa (0 conserva los archivos)", name)
+		}
+	}
+
+	positiveInts := map[string]int{
+		"workers.validation":              c.Workers.Validation,
+		"workers.concurrent_threshold":    c.Workers.ConcurrentThreshold,
+		"workers.files":                   c.Workers.Files,
+		"limits.requests_per_minute":      c.Limits.RequestsPerMinute,
+		"limits.request_burst":            c.Limits.RequestBurst,
+		"limits.auth_failures_per_minute": c.Limits.AuthFailuresPerMinute,
+		"limits.auth_failure_burst":       c.Limits.AuthFailureBurst,
+		"limits.concurrent_uploads":       c.Limits.ConcurrentUploads,
+		"limits.max_upload_files":         c.Limits.MaxUploadFiles,
+	}
+	for name, n := range positiveInts {
+		if n <= 0 {
+			addf("%s: debe ser mayor que cero", name)
+		}
+	}
+
+	positiveSizes := map[string]ByteSize{
+		"limits.max_upload_size":       c.Limits.MaxUploadSize,
+		"limits.max_uncompressed_size": c.Limits.MaxUncompressedSize,
+		"limits.multipart_memory":      c.Limits.MultipartMemory,
+		"limits.storage_quota":         c.Limits.StorageQuota,
+	}
+	for name, size := range positiveSizes {
+		if size <= 0 {
+			addf("%s: debe ser mayor que cero", name)
+		}
+	}
+
+	for _, code := range c.Validation.PhoneAreaCodes {
+		if !areaCodePattern.MatchString(code) {
+			addf("validation.phone_area_codes: '%s' no es una lada de 3 dígitos", code)
+		}
+	}
+	for _, domain := range c.Validation.EmailDomains {
+		if domain == "" || strings.ContainsAny(domain, "@ ") {
+			addf("validation.email_domains: '%s' no es un dominio válido", domain)
+		}
+	}
+
+	switch {
+	case c.Auth.Disabled && c.Environment == "production":
+		addf("auth.disabled: la autenticación es obligatoria en producción")
+	case c.Auth.Disabled && c.Auth.Enabled():
+		addf("auth.disabled: no puede combinarse con API keys ni claves JWT")
+	case !c.Auth.Disabled && !c.Auth.Enabled():
+		addf("auth: configure api_keys, jwt_secret o jwt_public_key_file (o auth.disabled: true para permitir el acceso anónimo de solo lectura fuera de producción)")
+	}
+	for i, apiKey := range c.Auth.APIKeys {
+		if apiKey.Name == "" || apiKey.Key == "" {
+			addf("auth.api_keys[%d]: falta el nombre o la clave", i)
+			continue
+		}
+		if placeholderAPIKeys[apiKey.Key] {
+			addf("auth.api_keys[%d] (%s): la clave es el valor de ejemplo; genere una clave propia", i, apiKey.Name)
+		}
+		if apiKey.Role != "" {
+			if _, err := models.ParseRole(apiKey.Role); err != nil {
+				addf("auth.api_keys[%d] (%s): %v", i, apiKey.Name, err)
+			}
+		}
+		if err := tenant.Validate(tenant.Normalize(apiKey.Tenant)); err != nil {
+			addf("auth.api_keys[%d] (%s): %v", i, apiKey.Name, err)
+		}
+	}
+
+	switch c.Storage.Driver {
+	case "local":
+		if c.Storage.LocalRoot == "" {
+			addf("storage.local_root: no puede estar vacío")
+		}
+	case "s3":
+		if c.Storage.S3.Bucket == "" {
+			addf("storage.s3.bucket: es obligatorio con el driver s3")
+		}
+		if c.Storage.S3.AccessKeyID == "" || c.Storage.S3.SecretAccessKey == "" {
+			addf("storage.s3: faltan access_key_id o secret_access_key")
+		}
+		if c.Storage.S3.Endpoint != "" {
+			u, err := url.Parse(c.Storage.S3.Endpoint)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				addf("storage.s3.endpoint: '%s' no es una URL http(s)", c.Storage.S3.Endpoint)
+			}
+		}
+	default:
+		addf("storage.driver: '%s' no es local ni s3", c.Storage.Driver)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	// Los mapas se recorren en orden aleatorio
+	sort.Strings(problems)
+	return errors.New("configuración inválida:\n  - " + strings.Join(problems, "\n  - "))
+}
+
+// Redacted obtiene la configuración como un mapa, con las mismas claves que
+// el archivo YAML y los secretos ocultos, para mostrarla sin exponerlos
+func (c *Config) Redacted() (map[string]interface{}, error) {
+	copied := *c
+	copied.Auth.APIKeys = make([]APIKey, len(c.Auth.APIKeys))
+	for i, apiKey := range c.Auth.APIKeys {
+		apiKey.Key = redacted
+		copied.Auth.APIKeys[i] = apiKey
+	}
+	if copied.Auth.JWTSecret != "" {
+		copied.Auth.JWTSecret = redacted
+	}
+	if copied.Storage.S3.SecretAccessKey != "" {
+		copied.Storage.S3.SecretAccessKey = redacted
+	}
+
+	// Se pasa por YAML para que las duraciones y los tamaños se muestren con
+	// el mismo formato que en el archivo
+	data, err := yaml.Marshal(&copied)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
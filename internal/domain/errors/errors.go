@@ -1,6 +1,9 @@
 package errors
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type AppError struct {
 	Code    string `json:"code"`
@@ -42,6 +45,96 @@ var (
 		Code:    "INVALID_EXCEL_STRUCTURE",
 		Message: "La estructura del archivo Excel no es válida",
 	}
+
+	ErrUploadNotFound = &AppError{
+		Code:    "UPLOAD_NOT_FOUND",
+		Message: "Archivo subido no encontrado",
+	}
+
+	ErrJobNotFound = &AppError{
+		Code:    "JOB_NOT_FOUND",
+		Message: "Trabajo no encontrado",
+	}
+
+	ErrJobFinished = &AppError{
+		Code:    "JOB_FINISHED",
+		Message: "El trabajo ya terminó y no puede cancelarse",
+	}
+
+	ErrOperationCancelled = &AppError{
+		Code:    "OPERATION_CANCELLED",
+		Message: "La operación fue cancelada",
+	}
+
+	ErrOperationTimeout = &AppError{
+		Code:    "OPERATION_TIMEOUT",
+		Message: "La operación excedió el tiempo máximo permitido",
+	}
+
+	ErrDatasetNotFound = &AppError{
+		Code:    "DATASET_NOT_FOUND",
+		Message: "Dataset no encontrado",
+	}
+
+	ErrDatasetAlreadyExists = &AppError{
+		Code:    "DATASET_ALREADY_EXISTS",
+		Message: "Ya existe un dataset con este nombre",
+	}
+
+	ErrInvalidDatasetName = &AppError{
+		Code:    "INVALID_DATASET_NAME",
+		Message: "Nombre de dataset inválido. Use letras, números, '-', '_' o '.' (máximo 64 caracteres)",
+	}
+
+	ErrInvalidCursor = &AppError{
+		Code:    "INVALID_CURSOR",
+		Message: "Cursor de paginación inválido o generado con otro ordenamiento",
+	}
+
+	ErrSavedFilterNotFound = &AppError{
+		Code:    "SAVED_FILTER_NOT_FOUND",
+		Message: "Filtro guardado no encontrado",
+	}
+
+	ErrSavedFilterAlreadyExists = &AppError{
+		Code:    "SAVED_FILTER_ALREADY_EXISTS",
+		Message: "Ya existe un filtro guardado con ese nombre",
+	}
+
+	ErrInvalidSavedFilterName = &AppError{
+		Code:    "INVALID_SAVED_FILTER_NAME",
+		Message: "Nombre de filtro inválido. Use letras, números, '-', '_' o '.' (máximo 64 caracteres)",
+	}
+
+	ErrInvalidQuery = &AppError{
+		Code:    "INVALID_QUERY",
+		Message: "Expresión de filtro inválida",
+	}
+
+	ErrFileTooLarge = &AppError{
+		Code:    "FILE_TOO_LARGE",
+		Message: "El archivo excede el tamaño máximo permitido",
+	}
+
+	ErrArchiveTooLarge = &AppError{
+		Code:    "ARCHIVE_TOO_LARGE",
+		Message: "El archivo descomprimido excede el tamaño máximo permitido",
+	}
+
+	ErrExportNotFound = &AppError{
+		Code:    "EXPORT_NOT_FOUND",
+		Message: "Archivo exportado no encontrado",
+	}
+
+	ErrStorageQuotaExceeded = &AppError{
+		Code:    "STORAGE_QUOTA_EXCEEDED",
+		Message: "Se excedió el espacio de almacenamiento disponible. Elimine archivos subidos para liberar espacio",
+	}
+
+	ErrShuttingDown = &AppError{
+		Code:    "SHUTTING_DOWN",
+		Message: "El servidor se está apagando; intente de nuevo en unos momentos",
+	}
 )
 
 // Funciones para crear errores específicos
@@ -52,6 +145,37 @@ func NewValidationError(field, message string) *AppError {
 	}
 }
 
+// NewQueryError error de sintaxis en una expresión de filtro
+func NewQueryError(message string) *AppError {
+	return &AppError{
+		Code:    ErrInvalidQuery.Code,
+		Message: fmt.Sprintf("%s: %s", ErrInvalidQuery.Message, message),
+	}
+}
+
+// NewStorageQuotaError cuota de almacenamiento excedida, con el espacio
+// utilizado y la cuota en bytes
+func NewStorageQuotaError(used, quota int64) *AppError {
+	return &AppError{
+		Code: ErrStorageQuotaExceeded.Code,
+		Message: fmt.Sprintf("%s (utilizado %s de %s)",
+			ErrStorageQuotaExceeded.Message, formatSize(used), formatSize(quota)),
+	}
+}
+
+// formatSize representa un tamaño en bytes con la unidad más adecuada
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
+
 func NewFileProcessingError(message string) *AppError {
 	return &AppError{
 		Code:    "FILE_PROCESSING_ERROR",
@@ -65,3 +189,12 @@ func NewDatabaseError(message string) *AppError {
 		Message: fmt.Sprintf("Error en base de datos: %s", message),
 	}
 }
+
+// NewContextError traduce el error de un contexto terminado: ErrOperationTimeout
+// si venció su plazo y ErrOperationCancelled en cualquier otro caso
+func NewContextError(err error) *AppError {
+	if err == context.DeadlineExceeded {
+		return ErrOperationTimeout
+	}
+	return ErrOperationCancelled
+}
@@ -6,26 +6,44 @@ import (
 )
 
 type Client struct {
-	ID        int               `json:"id"`
-	Clave     string            `json:"clave"`
-	Nombre    string            `json:"nombre"`
-	Correo    string            `json:"correo"`
-	Telefono  string            `json:"telefono"`
-	Errors    map[string]string `json:"errors,omitempty"`
-	IsValid   bool              `json:"is_valid"`
-	RowNumber int               `json:"row_number"`
-	CreatedAt time.Time         `json:"created_at"`
-	UpdatedAt time.Time         `json:"updated_at"`
+	ID           int               `json:"id"`
+	Clave        string            `json:"clave"`
+	Nombre       string            `json:"nombre"`
+	Correo       string            `json:"correo"`
+	Telefono     string            `json:"telefono"`
+	Errors       map[string]string `json:"errors,omitempty"`
+	IsValid      bool              `json:"is_valid"`
+	RowNumber    int               `json:"row_number"`
+	SourceUpload string            `json:"source_upload,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
 }
 
 type ClientFilter struct {
-	Clave     string `json:"clave,omitempty"`
-	Nombre    string `json:"nombre,omitempty"`
-	Correo    string `json:"correo,omitempty"`
-	Telefono  string `json:"telefono,omitempty"`
-	HasErrors *bool  `json:"has_errors,omitempty"`
-	Page      int    `json:"page,omitempty"`
-	Limit     int    `json:"limit,omitempty"`
+	Clave     string      `json:"clave,omitempty"`
+	Nombre    string      `json:"nombre,omitempty"`
+	Correo    string      `json:"correo,omitempty"`
+	Telefono  string      `json:"telefono,omitempty"`
+	HasErrors *bool       `json:"has_errors,omitempty"`
+	Sort      []SortField `json:"sort,omitempty"`
+	Page      int         `json:"page,omitempty"`
+	Limit     int         `json:"limit,omitempty"`
+	// Cursor posición opaca de un listado previo (paginación por cursor)
+	Cursor string `json:"cursor,omitempty"`
+	// Where condición adicional, normalmente una expresión de filtro compilada
+	Where ClientMatcher `json:"-"`
+}
+
+// ClientMatcher condición que puede cumplir o no un cliente
+type ClientMatcher interface {
+	Match(client *Client) bool
+}
+
+// ClientSearchResult cliente encontrado por la búsqueda de texto libre y su
+// relevancia
+type ClientSearchResult struct {
+	*Client
+	Score int `json:"score"`
 }
 
 type ValidationError struct {
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// clientFieldValues obtiene el valor de cada campo seleccionable del cliente,
+// por su nombre en JSON
+var clientFieldValues = map[string]func(c *Client) interface{}{
+	"id":            func(c *Client) interface{} { return c.ID },
+	"clave":         func(c *Client) interface{} { return c.Clave },
+	"nombre":        func(c *Client) interface{} { return c.Nombre },
+	"correo":        func(c *Client) interface{} { return c.Correo },
+	"telefono":      func(c *Client) interface{} { return c.Telefono },
+	"errors":        func(c *Client) interface{} { return c.errorsOrEmpty() },
+	"is_valid":      func(c *Client) interface{} { return c.IsValid },
+	"row_number":    func(c *Client) interface{} { return c.RowNumber },
+	"source_upload": func(c *Client) interface{} { return c.SourceUpload },
+	"created_at":    func(c *Client) interface{} { return c.CreatedAt },
+	"updated_at":    func(c *Client) interface{} { return c.UpdatedAt },
+}
+
+// ClientProjection cliente reducido a los campos seleccionados
+type ClientProjection map[string]interface{}
+
+// ParseClientFields interpreta una selección de campos separados por comas,
+// p. ej. "id,nombre,errors". Los campos repetidos se ignoran.
+func ParseClientFields(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		name := strings.TrimSpace(part)
+
+		if _, ok := clientFieldValues[name]; !ok {
+			return nil, fmt.Errorf("campo desconocido '%s'", name)
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			fields = append(fields, name)
+		}
+	}
+
+	return fields, nil
+}
+
+// Project obtiene solo los campos indicados del cliente, que deben provenir
+// de ParseClientFields
+func (c *Client) Project(fields []string) ClientProjection {
+	projection := make(ClientProjection, len(fields))
+	for _, field := range fields {
+		projection[field] = clientFieldValues[field](c)
+	}
+	return projection
+}
+
+// errorsOrEmpty devuelve los errores del cliente, o un mapa vacío si no tiene
+func (c *Client) errorsOrEmpty() map[string]string {
+	if c.Errors == nil {
+		return map[string]string{}
+	}
+	return c.Errors
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// ClientPage página de un listado de clientes
+type ClientPage struct {
+	Clients []*Client `json:"clients"`
+	// Total clientes que cumplen el filtro, sin paginar
+	Total      int    `json:"total"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	TotalPages int    `json:"total_pages"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// ClientCursor posición dentro de un listado ordenado. Se identifica por los
+// valores del cliente en el borde de la página y no por su índice, así que
+// sigue siendo válido aunque se agreguen o eliminen clientes.
+type ClientCursor struct {
+	// Sort ordenamiento con el que se generó, en formato FormatSortFields
+	Sort string `json:"s"`
+	// Before indica que se piden los clientes anteriores a Key
+	Before bool          `json:"b,omitempty"`
+	Key    ClientSortKey `json:"k"`
+}
+
+// NewClientCursor crea un cursor en la posición de client. Solo se guardan
+// el ID y los campos del ordenamiento, que son los que se comparan.
+func NewClientCursor(client *Client, fields []SortField, before bool) *ClientCursor {
+	full := client.SortKey()
+	key := ClientSortKey{ID: full.ID}
+
+	for _, field := range fields {
+		switch field.Field {
+		case "clave":
+			key.Clave = full.Clave
+		case "nombre":
+			key.Nombre = full.Nombre
+		case "correo":
+			key.Correo = full.Correo
+		case "telefono":
+			key.Telefono = full.Telefono
+		case "is_valid":
+			key.IsValid = full.IsValid
+		case "errors":
+			key.ErrorCount = full.ErrorCount
+		case "row_number":
+			key.RowNumber = full.RowNumber
+		case "source_upload":
+			key.SourceUpload = full.SourceUpload
+		case "created_at":
+			key.CreatedAt = full.CreatedAt
+		case "updated_at":
+			key.UpdatedAt = full.UpdatedAt
+		}
+	}
+
+	return &ClientCursor{
+		Sort:   FormatSortFields(fields),
+		Before: before,
+		Key:    key,
+	}
+}
+
+// Encode codifica el cursor como una cadena opaca apta para URLs
+func (c *ClientCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeClientCursor decodifica un cursor generado por Encode
+func DecodeClientCursor(value string) (*ClientCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor ClientCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
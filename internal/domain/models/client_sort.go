@@ -0,0 +1,208 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortField campo de ordenamiento de clientes
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// ClientSortKey valores de un cliente por los que se puede ordenar. Se guarda
+// en los cursores de paginación para ubicar la posición sin depender de índices.
+type ClientSortKey struct {
+	ID           int       `json:"id"`
+	Clave        string    `json:"clave,omitempty"`
+	Nombre       string    `json:"nombre,omitempty"`
+	Correo       string    `json:"correo,omitempty"`
+	Telefono     string    `json:"telefono,omitempty"`
+	IsValid      bool      `json:"is_valid,omitempty"`
+	ErrorCount   int       `json:"errors,omitempty"`
+	RowNumber    int       `json:"row_number,omitempty"`
+	SourceUpload string    `json:"source_upload,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitzero"`
+	UpdatedAt    time.Time `json:"updated_at,omitzero"`
+}
+
+// SortKey obtiene los valores ordenables del cliente
+func (c *Client) SortKey() ClientSortKey {
+	return ClientSortKey{
+		ID:           c.ID,
+		Clave:        c.Clave,
+		Nombre:       c.Nombre,
+		Correo:       c.Correo,
+		Telefono:     c.Telefono,
+		IsValid:      c.IsValid,
+		ErrorCount:   len(c.Errors),
+		RowNumber:    c.RowNumber,
+		SourceUpload: c.SourceUpload,
+		CreatedAt:    c.CreatedAt,
+		UpdatedAt:    c.UpdatedAt,
+	}
+}
+
+// clientComparators compara dos clientes por cada campo ordenable; devuelve
+// un valor negativo, cero o positivo como strings.Compare
+var clientComparators = map[string]func(a, b *ClientSortKey) int{
+	"id":            func(a, b *ClientSortKey) int { return compareInts(a.ID, b.ID) },
+	"clave":         func(a, b *ClientSortKey) int { return compareNumericStrings(a.Clave, b.Clave) },
+	"nombre":        func(a, b *ClientSortKey) int { return compareFold(a.Nombre, b.Nombre) },
+	"correo":        func(a, b *ClientSortKey) int { return compareFold(a.Correo, b.Correo) },
+	"telefono":      func(a, b *ClientSortKey) int { return strings.Compare(a.Telefono, b.Telefono) },
+	"is_valid":      func(a, b *ClientSortKey) int { return compareBools(a.IsValid, b.IsValid) },
+	"errors":        func(a, b *ClientSortKey) int { return compareInts(a.ErrorCount, b.ErrorCount) },
+	"row_number":    func(a, b *ClientSortKey) int { return compareInts(a.RowNumber, b.RowNumber) },
+	"source_upload": func(a, b *ClientSortKey) int { return strings.Compare(a.SourceUpload, b.SourceUpload) },
+	"created_at":    func(a, b *ClientSortKey) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"updated_at":    func(a, b *ClientSortKey) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+}
+
+// IsSortableClientField indica si se puede ordenar por el campo indicado
+func IsSortableClientField(field string) bool {
+	_, exists := clientComparators[field]
+	return exists
+}
+
+// CompareSortKeys compara dos claves según los campos indicados, en orden de
+// prioridad. Los empates se resuelven por ID, de modo que el orden es total.
+func CompareSortKeys(a, b *ClientSortKey, fields []SortField) int {
+	for _, field := range fields {
+		compare, exists := clientComparators[field.Field]
+		if !exists {
+			continue
+		}
+
+		result := compare(a, b)
+		if result == 0 {
+			continue
+		}
+		if field.Desc {
+			return -result
+		}
+		return result
+	}
+
+	return compareInts(a.ID, b.ID)
+}
+
+// SortClients ordena los clientes en su lugar por los campos indicados, en
+// orden de prioridad; los empates se resuelven por ID
+func SortClients(clients []*Client, fields []SortField) {
+	if len(fields) == 0 {
+		return
+	}
+
+	keys := make([]ClientSortKey, len(clients))
+	for i, client := range clients {
+		keys[i] = client.SortKey()
+	}
+
+	sort.Sort(&clientSorter{clients: clients, keys: keys, fields: fields})
+}
+
+// ParseSortFields interpreta un ordenamiento con campos separados por comas,
+// p. ej. "nombre,-row_number"; un "-" inicial indica orden descendente
+func ParseSortFields(value string) ([]SortField, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimPrefix(strings.TrimPrefix(part, "-"), "+")
+
+		if !IsSortableClientField(name) {
+			return nil, fmt.Errorf("no se puede ordenar por '%s'", part)
+		}
+
+		fields = append(fields, SortField{Field: name, Desc: desc})
+	}
+
+	return fields, nil
+}
+
+// FormatSortFields devuelve la representación textual de un ordenamiento,
+// p. ej. "nombre,-row_number"
+func FormatSortFields(fields []SortField) string {
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field.Field
+		if field.Desc {
+			parts[i] = "-" + field.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// clientSorter ordena clientes junto con sus claves precalculadas
+type clientSorter struct {
+	clients []*Client
+	keys    []ClientSortKey
+	fields  []SortField
+}
+
+func (s *clientSorter) Len() int { return len(s.clients) }
+
+func (s *clientSorter) Less(i, j int) bool {
+	return CompareSortKeys(&s.keys[i], &s.keys[j], s.fields) < 0
+}
+
+func (s *clientSorter) Swap(i, j int) {
+	s.clients[i], s.clients[j] = s.clients[j], s.clients[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareBools(a, b bool) int {
+	if a == b {
+		return 0
+	}
+	if !a {
+		return -1
+	}
+	return 1
+}
+
+// compareFold compara cadenas sin distinguir mayúsculas
+func compareFold(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// compareNumericStrings compara numéricamente las cadenas que son números;
+// estas van antes que las demás, que se comparan como texto
+func compareNumericStrings(a, b string) int {
+	na, errA := strconv.ParseInt(a, 10, 64)
+	nb, errB := strconv.ParseInt(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		}
+		return 0
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
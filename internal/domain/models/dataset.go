@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DefaultDatasetName nombre del dataset usado cuando no se especifica uno
+const DefaultDatasetName = "default"
+
+// Dataset representa un conjunto de clientes identificado por nombre
+type Dataset struct {
+	Name        string    `json:"name"`
+	ClientCount int       `json:"client_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// ExportedFile archivo generado por una exportación. Se almacena con un ID
+// opaco y se descarga con el nombre solicitado.
+type ExportedFile struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+}
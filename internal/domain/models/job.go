@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// JobStatus estado de un trabajo en segundo plano
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusCompleted JobStatus = "completed"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+// Tipos de trabajo
+const (
+	JobTypeImport     = "import"
+	JobTypeValidation = "validation"
+)
+
+// Etapas del procesamiento reportadas en el avance de un trabajo
+const (
+	StageQueued             = "queued"
+	StageFileSaved          = "file_saved"
+	StageHeadersValidated   = "headers_validated"
+	StageReading            = "reading"
+	StageValidating         = "validating"
+	StageCheckingDuplicates = "checking_duplicates"
+	StageDuplicatesChecked  = "duplicates_checked"
+	StageStoring            = "storing"
+	StageDone               = "done"
+)
+
+// Tipos de evento de avance además de los cambios de etapa
+const (
+	EventRowsParsed    = "rows_parsed"
+	EventRowsValidated = "rows_validated"
+	EventDone          = "done"
+	EventFailed        = "failed"
+	EventCancelled     = "cancelled"
+)
+
+// JobProgress avance de un trabajo
+type JobProgress struct {
+	Stage         string `json:"stage"`
+	RowsRead      int    `json:"rows_read"`
+	RowsValidated int    `json:"rows_validated"`
+	ErrorsFound   int    `json:"errors_found"`
+}
+
+// Job trabajo en segundo plano con su avance y resultado
+type Job struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Status     JobStatus         `json:"status"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Progress   JobProgress       `json:"progress"`
+	Result     interface{}       `json:"result,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	StartedAt  *time.Time        `json:"started_at,omitempty"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time        `json:"expires_at,omitempty"`
+}
+
+// JobEvent evento de avance de un trabajo. Type es una etapa o uno de los
+// tipos de evento definidos arriba.
+type JobEvent struct {
+	ID       int         `json:"id"`
+	JobID    string      `json:"job_id"`
+	Type     string      `json:"type"`
+	Progress JobProgress `json:"progress"`
+	Message  string      `json:"message,omitempty"`
+	Time     time.Time   `json:"time"`
+}
+
+// IsTerminal indica si el evento marca el fin del trabajo
+func (e *JobEvent) IsTerminal() bool {
+	return e.Type == EventDone || e.Type == EventFailed || e.Type == EventCancelled
+}
+
+// IsFinished indica si el trabajo ya terminó (con éxito, error o cancelado)
+func (j *Job) IsFinished() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
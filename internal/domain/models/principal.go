@@ -0,0 +1,20 @@
+package models
+
+// Métodos de autenticación
+const (
+	AuthMethodAPIKey = "api_key"
+	AuthMethodJWT    = "jwt"
+	// AuthMethodNone principal anónimo cuando la autenticación está deshabilitada
+	AuthMethodNone = "none"
+)
+
+// Principal identidad autenticada que realiza una petición
+type Principal struct {
+	Subject    string `json:"subject"`
+	AuthMethod string `json:"auth_method"`
+	Role       Role   `json:"role"`
+	// Tenant inquilino al que pertenecen los datos que ve el principal
+	Tenant string `json:"tenant"`
+	// Claims contenido del token, solo para autenticación JWT
+	Claims map[string]interface{} `json:"claims,omitempty"`
+}
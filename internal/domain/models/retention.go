@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// FileKind tipo de archivo almacenado; cada tipo tiene su plazo de retención
+type FileKind string
+
+const (
+	FileKindUpload   FileKind = "upload"
+	FileKindExport   FileKind = "export"
+	FileKindTemplate FileKind = "template"
+)
+
+// ExpiredFile archivo almacenado cuyo plazo de retención venció
+type ExpiredFile struct {
+	Key    string   `json:"key"`
+	Kind   FileKind `json:"kind"`
+	Tenant string   `json:"tenant"`
+	Size   int64    `json:"size"`
+	// LastUsedAt última modificación del archivo o, si es un upload, último
+	// upload registrado que lo utiliza
+	LastUsedAt time.Time `json:"last_used_at"`
+	// Removed indica si el archivo se eliminó; siempre false en un simulacro
+	Removed bool   `json:"removed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RetentionReport archivos vencidos encontrados (y eliminados, salvo en un
+// simulacro) en una aplicación de la política de retención
+type RetentionReport struct {
+	DryRun bool      `json:"dry_run"`
+	RunAt  time.Time `json:"run_at"`
+	// Policies plazo de retención de cada tipo de archivo; "0s" si se
+	// conservan indefinidamente
+	Policies   map[FileKind]string `json:"policies"`
+	Files      []*ExpiredFile      `json:"files"`
+	TotalFiles int                 `json:"total_files"`
+	TotalBytes int64               `json:"total_bytes"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "fmt"
+
+// Role rol de un principal. Cada rol incluye los permisos de los anteriores:
+// viewer consulta y exporta, editor además sube y edita, y admin además
+// vacía datasets y elimina archivos.
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+// roleLevels nivel de privilegio de cada rol
+var roleLevels = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// ParseRole interpreta el nombre de un rol
+func ParseRole(value string) (Role, error) {
+	role := Role(value)
+	if _, ok := roleLevels[role]; !ok {
+		return "", fmt.Errorf("rol desconocido '%s' (use viewer, editor o admin)", value)
+	}
+	return role, nil
+}
+
+// Allows indica si el rol incluye los permisos de required
+func (r Role) Allows(required Role) bool {
+	return roleLevels[r] >= roleLevels[required]
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// SavedFilter definición de filtro guardada con nombre, reutilizable desde el
+// listado, la exportación y las estadísticas con ?saved_filter=<nombre>
+type SavedFilter struct {
+	Name string `json:"name"`
+	// Tenant inquilino propietario; los nombres son únicos por inquilino
+	Tenant      string `json:"tenant,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Filter expresión de filtro, p. ej. correo:ends:@hotmail.com AND errors:exists
+	Filter string `json:"filter"`
+	// Sort ordenamiento por defecto, con el formato del parámetro sort
+	Sort      string    `json:"sort,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// UploadStatus estado del procesamiento de un archivo subido
+type UploadStatus string
+
+const (
+	UploadStatusProcessing UploadStatus = "processing"
+	UploadStatusCompleted  UploadStatus = "completed"
+	UploadStatusFailed     UploadStatus = "failed"
+	UploadStatusCancelled  UploadStatus = "cancelled"
+	// UploadStatusExpired el archivo se eliminó al vencer su plazo de
+	// retención; el registro conserva el resultado del procesamiento
+	UploadStatusExpired UploadStatus = "expired"
+)
+
+// Upload metadatos de un archivo subido y del resultado de su procesamiento
+type Upload struct {
+	ID           string        `json:"id"`
+	OriginalName string        `json:"original_name"`
+	StoredName   string        `json:"stored_name"`
+	SHA256       string        `json:"sha256"`
+	Size         int64         `json:"size"`
+	Uploader     string        `json:"uploader"`
+	Tenant       string        `json:"tenant"`
+	Dataset      string        `json:"dataset"`
+	UploadedAt   time.Time     `json:"uploaded_at"`
+	ProcessedAt  *time.Time    `json:"processed_at,omitempty"`
+	TotalRows    int           `json:"total_rows"`
+	ValidCount   int           `json:"valid_count"`
+	InvalidCount int           `json:"invalid_count"`
+	Status       UploadStatus  `json:"status"`
+	Error        string        `json:"error,omitempty"`
+	Timings      *StageTimings `json:"timings,omitempty"`
+}
+
+// StorageUsage espacio ocupado por los archivos de un inquilino
+type StorageUsage struct {
+	UsedBytes int64 `json:"used_bytes"`
+	// QuotaBytes espacio máximo; 0 si no hay límite
+	QuotaBytes int64 `json:"quota_bytes,omitempty"`
+}
+
+// StageTimings duración en milisegundos de cada etapa del procesamiento de un
+// upload. La lectura y la validación se intercalan por bloques, así que cada
+// una suma solo el tiempo propio.
+type StageTimings struct {
+	SaveMs       int64 `json:"save_ms"`
+	ParseMs      int64 `json:"parse_ms"`
+	ValidateMs   int64 `json:"validate_ms"`
+	DuplicatesMs int64 `json:"duplicates_ms"`
+	StoreMs      int64 `json:"store_ms"`
+	TotalMs      int64 `json:"total_ms"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// ValidationRules reglas de validación configurables por inquilino. Las
+// listas vacías usan las reglas por defecto.
+type ValidationRules struct {
+	// Region nombre de la región de las ladas, usado en los mensajes de error
+	Region         string   `json:"region,omitempty" yaml:"region,omitempty"`
+	PhoneAreaCodes []string `json:"phone_area_codes,omitempty" yaml:"phone_area_codes,omitempty"`
+	EmailDomains   []string `json:"email_domains,omitempty" yaml:"email_domains,omitempty"`
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/middleware"
+	"client-data-compiler/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct{}
+
+func NewAuthHandler() *AuthHandler {
+	return &AuthHandler{}
+}
+
+// Me obtiene el principal autenticado de la petición
+func (h *AuthHandler) Me(c *gin.Context) {
+	principal, _ := middleware.PrincipalFromContext(c)
+
+	message := "Principal autenticado"
+	if principal.AuthMethod == models.AuthMethodNone {
+		message = "Autenticación deshabilitada"
+	}
+
+	response.Success(c, message, gin.H{"principal": principal})
+}
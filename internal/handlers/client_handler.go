@@ -1,45 +1,45 @@
 package handlers
 
 import (
+	"client-data-compiler/internal/config"
+	"client-data-compiler/internal/domain/errors"
 	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/query"
 	"client-data-compiler/internal/services"
+	"client-data-compiler/internal/utils"
 	"client-data-compiler/pkg/response"
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type ClientHandler struct {
-	clientService services.ClientService
+	clientService      services.ClientService
+	jobService         services.JobService
+	savedFilterService services.SavedFilterService
+	timeouts           config.Timeouts
 }
 
-func NewClientHandler(clientService services.ClientService) *ClientHandler {
+func NewClientHandler(clientService services.ClientService, jobService services.JobService, savedFilterService services.SavedFilterService, timeouts config.Timeouts) *ClientHandler {
 	return &ClientHandler{
-		clientService: clientService,
+		clientService:      clientService,
+		jobService:         jobService,
+		savedFilterService: savedFilterService,
+		timeouts:           timeouts,
 	}
 }
 
 // GetClients obtiene clientes con filtros opcionales
 func (h *ClientHandler) GetClients(c *gin.Context) {
-	log.Printf("📥 Petición GetClients recibida desde: %s", c.Request.Header.Get("Origin"))
-	log.Printf("📊 Query params: %s", c.Request.URL.RawQuery)
-
 	// Construir filtros desde query parameters
-	filter := &models.ClientFilter{
-		Clave:    c.Query("clave"),
-		Nombre:   c.Query("nombre"),
-		Correo:   c.Query("correo"),
-		Telefono: c.Query("telefono"),
-	}
-
-	// Filtro por errores
-	if hasErrorsStr := c.Query("has_errors"); hasErrorsStr != "" {
-		if hasErrors, err := strconv.ParseBool(hasErrorsStr); err == nil {
-			filter.HasErrors = &hasErrors
-		}
+	filter, err := h.clientFilterFromRequest(c)
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
 	}
 
 	// Paginación
@@ -55,32 +55,47 @@ func (h *ClientHandler) GetClients(c *gin.Context) {
 		}
 	}
 
-	log.Printf("🔍 Filtros aplicados: %+v", filter)
+	// Paginación por cursor (tiene prioridad sobre page)
+	filter.Cursor = c.Query("cursor")
 
-	// Obtener clientes
-	clients, err := h.clientService.GetClients(filter)
+	// Campos a incluir de cada cliente, p. ej. fields=id,nombre,errors
+	fields, err := parseFieldsParam(c.Query("fields"))
 	if err != nil {
-		log.Printf("❌ Error obteniendo clientes: %v", err)
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
-	// Obtener total de clientes (sin filtros para paginación)
-	totalClients := h.clientService.GetClientCount()
+	dataset := datasetFromRequest(c)
+
+	// Obtener clientes
+	page, err := h.clientService.GetClients(c.Request.Context(), dataset, filter)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error obteniendo clientes", "dataset", dataset, "error", err)
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
 
-	log.Printf("✅ Enviando respuesta: %d clientes encontrados", len(clients))
+	// Los filtros no se registran porque pueden contener datos personales
+	slog.DebugContext(c.Request.Context(), "Clientes obtenidos", "dataset", dataset,
+		"page", page.Page, "limit", page.Limit, "returned", len(page.Clients), "total", page.Total)
 
 	responseData := gin.H{
-		"clients": clients,
-		"total":   totalClients,
-		"page":    filter.Page,
-		"limit":   filter.Limit,
+		"dataset":     dataset,
+		"clients":     projectClients(page.Clients, fields),
+		"total":       page.Total,
+		"page":        page.Page,
+		"limit":       page.Limit,
+		"total_pages": page.TotalPages,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+		"links":       paginationLinks(c, page),
 	}
 
 	response.Success(c, "Clientes obtenidos exitosamente", responseData)
 }
 
-// SearchClients busca clientes por texto libre
+// SearchClients busca clientes por texto libre, sin distinguir mayúsculas
+// ni acentos, y los devuelve ordenados por relevancia
 func (h *ClientHandler) SearchClients(c *gin.Context) {
 	searchTerm := c.Query("q")
 	if searchTerm == "" {
@@ -88,33 +103,118 @@ func (h *ClientHandler) SearchClients(c *gin.Context) {
 		return
 	}
 
-	// Para búsqueda libre, obtenemos todos los clientes y filtramos manualmente
-	allClients, err := h.clientService.GetClients(nil)
+	fields, err := parseFieldsParam(c.Query("fields"))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
-	// Buscar en todos los campos
-	var results []*models.Client
-	searchTermLower := strings.ToLower(searchTerm)
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+
+	dataset := datasetFromRequest(c)
+	results, total, err := h.clientService.SearchClients(c.Request.Context(), dataset, searchTerm, limit)
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
 
-	for _, client := range allClients {
-		if strings.Contains(strings.ToLower(client.Clave), searchTermLower) ||
-			strings.Contains(strings.ToLower(client.Nombre), searchTermLower) ||
-			strings.Contains(strings.ToLower(client.Correo), searchTermLower) ||
-			strings.Contains(client.Telefono, searchTerm) {
-			results = append(results, client)
+	// Con selección de campos se conserva siempre la relevancia
+	var clients interface{} = results
+	if fields != nil {
+		projections := make([]models.ClientProjection, len(results))
+		for i, result := range results {
+			projections[i] = result.Project(fields)
+			projections[i]["score"] = result.Score
 		}
+		clients = projections
 	}
 
 	response.Success(c, "Búsqueda completada", gin.H{
-		"clients":     results,
-		"total":       len(results),
+		"dataset":     dataset,
+		"clients":     clients,
+		"total":       total,
 		"search_term": searchTerm,
 	})
 }
 
+// LookupClients busca clientes por coincidencia exacta de clave, correo o
+// teléfono usando los índices del dataset
+func (h *ClientHandler) LookupClients(c *gin.Context) {
+	var field, value string
+	for _, candidate := range []string{"clave", "correo", "telefono"} {
+		if v := c.Query(candidate); v != "" {
+			field, value = candidate, v
+			break
+		}
+	}
+
+	if field == "" {
+		response.Error(c, http.StatusBadRequest, "Debe indicar clave, correo o telefono")
+		return
+	}
+
+	dataset := datasetFromRequest(c)
+	clients, err := h.clientService.LookupClients(c.Request.Context(), dataset, field, value)
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	response.Success(c, "Búsqueda completada", gin.H{
+		"dataset": dataset,
+		"field":   field,
+		"value":   value,
+		"clients": clients,
+		"total":   len(clients),
+	})
+}
+
+// BulkDeleteRequest cuerpo de la eliminación masiva de clientes
+type BulkDeleteRequest struct {
+	Filter string `json:"filter" binding:"required"`
+	DryRun bool   `json:"dry_run"`
+}
+
+// BulkDelete elimina los clientes que cumplen una expresión de filtro. Con
+// dry_run solo informa cuántos se eliminarían.
+func (h *ClientHandler) BulkDelete(c *gin.Context) {
+	var req BulkDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Debe indicar la expresión de filtro: "+err.Error())
+		return
+	}
+
+	where, err := parseFilterParam(req.Filter)
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	dataset := datasetFromRequest(c)
+	deleted, err := h.clientService.DeleteClients(c.Request.Context(), dataset, where, req.DryRun)
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	message := "Clientes eliminados exitosamente"
+	if req.DryRun {
+		message = "Simulación completada, no se eliminó ningún cliente"
+	}
+
+	response.Success(c, message, gin.H{
+		"dataset": dataset,
+		"filter":  where.String(),
+		"deleted": deleted,
+		"dry_run": req.DryRun,
+	})
+}
+
 // GetClientByID obtiene un cliente específico por ID
 func (h *ClientHandler) GetClientByID(c *gin.Context) {
 	idStr := c.Param("id")
@@ -124,9 +224,9 @@ func (h *ClientHandler) GetClientByID(c *gin.Context) {
 		return
 	}
 
-	client, err := h.clientService.GetClientByID(id)
+	client, err := h.clientService.GetClientByID(c.Request.Context(), datasetFromRequest(c), id)
 	if err != nil {
-		response.Error(c, http.StatusNotFound, err.Error())
+		respondServiceError(c, err, http.StatusNotFound)
 		return
 	}
 
@@ -148,9 +248,9 @@ func (h *ClientHandler) UpdateClient(c *gin.Context) {
 		return
 	}
 
-	updatedClient, err := h.clientService.UpdateClient(id, &updateData)
+	updatedClient, err := h.clientService.UpdateClient(c.Request.Context(), datasetFromRequest(c), id, &updateData)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		respondServiceError(c, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -166,43 +266,98 @@ func (h *ClientHandler) DeleteClient(c *gin.Context) {
 		return
 	}
 
-	if err := h.clientService.DeleteClient(id); err != nil {
-		response.Error(c, http.StatusNotFound, err.Error())
+	if err := h.clientService.DeleteClient(c.Request.Context(), datasetFromRequest(c), id); err != nil {
+		respondServiceError(c, err, http.StatusNotFound)
 		return
 	}
 
 	response.Success(c, "Cliente eliminado exitosamente", nil)
 }
 
-// ClearAll limpia todos los clientes de la memoria
+// ClearAll limpia todos los clientes del dataset
 func (h *ClientHandler) ClearAll(c *gin.Context) {
-	if err := h.clientService.ClearAllClients(); err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+	if err := h.clientService.ClearAllClients(c.Request.Context(), datasetFromRequest(c)); err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
 		return
 	}
 
 	response.Success(c, "Todos los clientes han sido eliminados", nil)
 }
 
-// ValidateAll valida todos los clientes cargados
+// ValidateAll valida todos los clientes cargados. Con async=true la validación
+// se ejecuta como trabajo en segundo plano cuyo avance puede seguirse por SSE.
+// Con stats_only=true la respuesta omite los clientes y solo incluye las
+// estadísticas; fields limita los campos de cada cliente.
 func (h *ClientHandler) ValidateAll(c *gin.Context) {
-	clients, err := h.clientService.ValidateAllClients()
+	dataset := datasetFromRequest(c)
+
+	if async, _ := strconv.ParseBool(c.Query("async")); async {
+		h.startValidationJob(c, dataset)
+		return
+	}
+
+	statsOnly, _ := strconv.ParseBool(c.Query("stats_only"))
+	fields, err := parseFieldsParam(c.Query("fields"))
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeouts.Validation)
+	defer cancel()
+
+	clients, err := h.clientService.ValidateAllClients(ctx, dataset, nil)
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
 		return
 	}
 
 	// Obtener estadísticas de validación
-	stats, _ := h.clientService.GetStats()
+	stats, err := h.clientService.GetStats(ctx, dataset, nil)
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
 
 	responseData := gin.H{
-		"clients": clients,
-		"stats":   stats,
+		"stats": stats,
+	}
+	if !statsOnly {
+		responseData["clients"] = projectClients(clients, fields)
 	}
 
 	response.Success(c, "Validación completada", responseData)
 }
 
+// startValidationJob inicia la validación del dataset en segundo plano
+func (h *ClientHandler) startValidationJob(c *gin.Context, dataset string) {
+	if _, err := h.clientService.GetDataset(c.Request.Context(), dataset); err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	metadata := map[string]string{"dataset": dataset}
+	job := h.jobService.Start(c.Request.Context(), models.JobTypeValidation, metadata, h.timeouts.Validation, func(ctx context.Context, progress services.ProgressReporter) (interface{}, error) {
+		clients, err := h.clientService.ValidateAllClients(ctx, dataset, progress)
+		if err != nil {
+			return nil, err
+		}
+
+		stats, err := h.clientService.GetStats(ctx, dataset, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return gin.H{
+			"dataset": dataset,
+			"total":   len(clients),
+			"stats":   stats,
+		}, nil
+	})
+
+	respondWithJob(c, h.jobService, job, "Validación completada", nil)
+}
+
 // ValidateSingle valida un cliente individual
 func (h *ClientHandler) ValidateSingle(c *gin.Context) {
 	var clientData models.Client
@@ -211,36 +366,198 @@ func (h *ClientHandler) ValidateSingle(c *gin.Context) {
 		return
 	}
 
-	validatedClient := h.clientService.ValidateClient(&clientData)
+	validatedClient := h.clientService.ValidateClient(c.Request.Context(), &clientData)
 
 	response.Success(c, "Cliente validado", gin.H{"client": validatedClient})
 }
 
-// ExportExcel exporta los clientes a un archivo Excel
+// ExportExcel exporta los clientes a un archivo Excel. Acepta los mismos
+// filtros y ordenamiento que el listado.
 func (h *ClientHandler) ExportExcel(c *gin.Context) {
 	filename := c.Query("filename")
 
-	filePath, err := h.clientService.ExportClientsToExcel(filename)
+	filter, err := h.clientFilterFromRequest(c)
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeouts.Export)
+	defer cancel()
+
+	export, err := h.clientService.ExportClientsToExcel(ctx, datasetFromRequest(c), filename, filter)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		respondServiceError(c, err, http.StatusInternalServerError)
 		return
 	}
 
 	responseData := gin.H{
-		"file_path": filePath,
-		"file_url":  "/files/" + filePath[8:], // Remover "uploads/" del path
+		"file_id":  export.ID,
+		"filename": export.Filename,
+		"file_url": "/api/exports/" + export.ID + "?filename=" + url.QueryEscape(export.Filename),
 	}
 
 	response.Success(c, "Archivo Excel exportado exitosamente", responseData)
 }
 
-// GetStats obtiene estadísticas de los clientes
+// DownloadExport descarga un archivo exportado por el inquilino. filename
+// indica el nombre con el que se descarga.
+func (h *ClientHandler) DownloadExport(c *gin.Context) {
+	file, err := h.clientService.GetExport(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	filename := utils.SanitizeFilename(c.DefaultQuery("filename", "clientes_exportados.xlsx"))
+	serveExcelFile(c, file, filename)
+}
+
+// GetStats obtiene estadísticas de los clientes. Acepta los mismos filtros
+// que el listado.
 func (h *ClientHandler) GetStats(c *gin.Context) {
-	stats, err := h.clientService.GetStats()
+	filter, err := h.clientFilterFromRequest(c)
 	if err != nil {
-		response.Error(c, http.StatusInternalServerError, err.Error())
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.clientService.GetStats(c.Request.Context(), datasetFromRequest(c), filter)
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
 		return
 	}
 
 	response.Success(c, "Estadísticas obtenidas exitosamente", gin.H{"stats": stats})
 }
+
+// clientFilterFromRequest construye el filtro de clientes a partir de los
+// parámetros clave, nombre, correo, telefono, has_errors, saved_filter, filter
+// y sort. La expresión de filter se combina con la del filtro guardado y sort
+// reemplaza su ordenamiento.
+func (h *ClientHandler) clientFilterFromRequest(c *gin.Context) (*models.ClientFilter, error) {
+	filter := &models.ClientFilter{
+		Clave:    c.Query("clave"),
+		Nombre:   c.Query("nombre"),
+		Correo:   c.Query("correo"),
+		Telefono: c.Query("telefono"),
+	}
+
+	// Filtro por errores
+	if hasErrorsStr := c.Query("has_errors"); hasErrorsStr != "" {
+		if hasErrors, err := strconv.ParseBool(hasErrorsStr); err == nil {
+			filter.HasErrors = &hasErrors
+		}
+	}
+
+	// Filtro guardado
+	var where query.Expr
+	if name := c.Query("saved_filter"); name != "" {
+		savedWhere, savedSort, err := h.savedFilterService.ResolveFilter(c.Request.Context(), name)
+		if err != nil {
+			return nil, err
+		}
+		where, filter.Sort = savedWhere, savedSort
+	}
+
+	// Expresión de filtro, p. ej. filter=correo:ends:@hotmail.com AND row_number>100
+	if value := c.Query("filter"); value != "" {
+		expr, err := parseFilterParam(value)
+		if err != nil {
+			return nil, err
+		}
+		if where != nil {
+			expr = query.And(where, expr)
+		}
+		where = expr
+	}
+	if where != nil {
+		filter.Where = where
+	}
+
+	// Ordenamiento, p. ej. sort=nombre,-row_number
+	if value := c.Query("sort"); value != "" {
+		sortFields, err := parseSortParam(value)
+		if err != nil {
+			return nil, err
+		}
+		filter.Sort = sortFields
+	}
+
+	return filter, nil
+}
+
+// parseFilterParam compila una expresión de filtro
+func parseFilterParam(value string) (query.Expr, error) {
+	expr, err := query.Parse(value)
+	if err != nil {
+		return nil, errors.NewQueryError(err.Error())
+	}
+	return expr, nil
+}
+
+// parseFieldsParam interpreta el parámetro fields de la petición
+func parseFieldsParam(value string) ([]string, error) {
+	fields, err := models.ParseClientFields(value)
+	if err != nil {
+		return nil, errors.NewValidationError("fields", err.Error())
+	}
+	return fields, nil
+}
+
+// projectClients reduce los clientes a los campos indicados; sin campos los
+// devuelve completos
+func projectClients(clients []*models.Client, fields []string) interface{} {
+	if fields == nil {
+		return clients
+	}
+
+	projections := make([]models.ClientProjection, len(clients))
+	for i, client := range clients {
+		projections[i] = client.Project(fields)
+	}
+	return projections
+}
+
+// parseSortParam interpreta el parámetro sort de la petición
+func parseSortParam(value string) ([]models.SortField, error) {
+	fields, err := models.ParseSortFields(value)
+	if err != nil {
+		return nil, errors.NewValidationError("sort", err.Error())
+	}
+	return fields, nil
+}
+
+// paginationLinks construye los enlaces a la página siguiente y anterior
+// conservando el resto de parámetros de la petición. En modo página se usan
+// números de página y en modo cursor, los cursores de la página.
+func paginationLinks(c *gin.Context, page *models.ClientPage) gin.H {
+	links := gin.H{"next": nil, "prev": nil}
+
+	link := func(param, value string) string {
+		query := c.Request.URL.Query()
+		query.Del("page")
+		query.Del("cursor")
+		query.Set(param, value)
+		return c.Request.URL.Path + "?" + query.Encode()
+	}
+
+	if page.Page > 0 {
+		if page.Page < page.TotalPages {
+			links["next"] = link("page", strconv.Itoa(page.Page+1))
+		}
+		if page.Page > 1 {
+			links["prev"] = link("page", strconv.Itoa(min(page.Page-1, max(page.TotalPages, 1))))
+		}
+		return links
+	}
+
+	if page.NextCursor != "" {
+		links["next"] = link("cursor", page.NextCursor)
+	}
+	if page.PrevCursor != "" {
+		links["prev"] = link("cursor", page.PrevCursor)
+	}
+
+	return links
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"client-data-compiler/internal/config"
+	"client-data-compiler/internal/tenant"
+	"client-data-compiler/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConfigHandler struct {
+	cfg *config.Config
+}
+
+func NewConfigHandler(cfg *config.Config) *ConfigHandler {
+	return &ConfigHandler{
+		cfg: cfg,
+	}
+}
+
+// GetConfig obtiene la configuración efectiva de la API con los secretos
+// ocultos. Como incluye la de todos los inquilinos, solo está disponible para
+// el inquilino por defecto.
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	if tenant.FromContext(c.Request.Context()) != tenant.Default {
+		response.Forbidden(c, "La configuración solo está disponible para el inquilino por defecto")
+		return
+	}
+
+	redacted, err := h.cfg.Redacted()
+	if err != nil {
+		response.InternalServerError(c, "Error obteniendo la configuración: "+err.Error())
+		return
+	}
+
+	response.Success(c, "Configuración obtenida", gin.H{"config": redacted})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"client-data-compiler/internal/domain/errors"
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/services"
+	"client-data-compiler/pkg/response"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DatasetHandler struct {
+	clientService services.ClientService
+}
+
+func NewDatasetHandler(clientService services.ClientService) *DatasetHandler {
+	return &DatasetHandler{
+		clientService: clientService,
+	}
+}
+
+// renameDatasetRequest cuerpo de la petición para renombrar un dataset
+type renameDatasetRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// ListDatasets obtiene la lista de datasets
+func (h *DatasetHandler) ListDatasets(c *gin.Context) {
+	datasets := h.clientService.ListDatasets(c.Request.Context())
+
+	response.Success(c, "Lista de datasets obtenida", gin.H{
+		"datasets": datasets,
+		"total":    len(datasets),
+	})
+}
+
+// GetDataset obtiene la información de un dataset
+func (h *DatasetHandler) GetDataset(c *gin.Context) {
+	dataset, err := h.clientService.GetDataset(c.Request.Context(), c.Param("dataset"))
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.Success(c, "Dataset encontrado", gin.H{"dataset": dataset})
+}
+
+// RenameDataset cambia el nombre de un dataset
+func (h *DatasetHandler) RenameDataset(c *gin.Context) {
+	var req renameDatasetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Datos inválidos: "+err.Error())
+		return
+	}
+
+	dataset, err := h.clientService.RenameDataset(c.Request.Context(), c.Param("dataset"), req.Name)
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.Success(c, "Dataset renombrado exitosamente", gin.H{"dataset": dataset})
+}
+
+// DeleteDataset elimina un dataset y todos sus clientes
+func (h *DatasetHandler) DeleteDataset(c *gin.Context) {
+	name := c.Param("dataset")
+
+	if err := h.clientService.DeleteDataset(c.Request.Context(), name); err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.Success(c, "Dataset eliminado exitosamente", gin.H{"dataset": name})
+}
+
+// Funciones auxiliares
+
+// datasetFromRequest obtiene el dataset objetivo desde la ruta, el query string
+// o el formulario. Si no se especifica, se usa el dataset por defecto.
+func datasetFromRequest(c *gin.Context) string {
+	if name := c.Param("dataset"); name != "" {
+		return name
+	}
+	if name := c.Query("dataset"); name != "" {
+		return name
+	}
+	if name := c.PostForm("dataset"); name != "" {
+		return name
+	}
+	return models.DefaultDatasetName
+}
+
+// respondServiceError responde con el código HTTP correspondiente a un error
+// de la aplicación, o con fallbackStatus si el error no es conocido
+func respondServiceError(c *gin.Context, err error, fallbackStatus int) {
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		response.Error(c, fallbackStatus, err.Error())
+		return
+	}
+
+	status := fallbackStatus
+	switch appErr.Code {
+	case errors.ErrClientNotFound.Code, errors.ErrDatasetNotFound.Code,
+		errors.ErrUploadNotFound.Code, errors.ErrJobNotFound.Code,
+		errors.ErrSavedFilterNotFound.Code, errors.ErrExportNotFound.Code:
+		status = http.StatusNotFound
+	case errors.ErrDuplicateClientKey.Code, errors.ErrDatasetAlreadyExists.Code,
+		errors.ErrJobFinished.Code, errors.ErrOperationCancelled.Code,
+		errors.ErrSavedFilterAlreadyExists.Code:
+		status = http.StatusConflict
+	case errors.ErrInvalidClientID.Code, errors.ErrInvalidDatasetName.Code,
+		errors.ErrInvalidFileFormat.Code, errors.ErrFileEmpty.Code,
+		errors.ErrInvalidExcelStructure.Code, errors.ErrInvalidCursor.Code,
+		errors.ErrInvalidQuery.Code, errors.ErrInvalidSavedFilterName.Code:
+		status = http.StatusBadRequest
+	case errors.ErrFileTooLarge.Code, errors.ErrStorageQuotaExceeded.Code,
+		errors.ErrArchiveTooLarge.Code:
+		status = http.StatusRequestEntityTooLarge
+	case errors.ErrOperationTimeout.Code:
+		status = http.StatusGatewayTimeout
+	case errors.ErrShuttingDown.Code:
+		status = http.StatusServiceUnavailable
+	}
+
+	response.ErrorWithCode(c, status, appErr.Code, appErr.Message)
+}
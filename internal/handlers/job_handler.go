@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"client-data-compiler/internal/domain/errors"
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/services"
+	"client-data-compiler/pkg/response"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+type JobHandler struct {
+	jobService services.JobService
+}
+
+func NewJobHandler(jobService services.JobService) *JobHandler {
+	return &JobHandler{
+		jobService: jobService,
+	}
+}
+
+// ListJobs obtiene la lista de trabajos en segundo plano
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	jobs := h.jobService.List(c.Request.Context())
+
+	response.Success(c, "Lista de trabajos obtenida", gin.H{
+		"jobs":  jobs,
+		"total": len(jobs),
+	})
+}
+
+// GetJob obtiene el estado, avance y resultado de un trabajo
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, err := h.jobService.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.Success(c, "Trabajo encontrado", gin.H{"job": job})
+}
+
+// CancelJob solicita la cancelación de un trabajo
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	job, err := h.jobService.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.Success(c, "Cancelación solicitada", gin.H{"job": job})
+}
+
+// StreamJobEvents envía por Server-Sent Events el avance de un trabajo hasta
+// que termina. Admite Last-Event-ID para reanudar sin perder eventos.
+func (h *JobHandler) StreamJobEvents(c *gin.Context) {
+	h.streamEvents(c, c.Param("id"))
+}
+
+// StreamUploadEvents envía por Server-Sent Events el avance del trabajo que
+// procesa un upload
+func (h *JobHandler) StreamUploadEvents(c *gin.Context) {
+	uploadID := c.Param("id")
+
+	for _, job := range h.jobService.List(c.Request.Context()) {
+		if jobProcessesUpload(job, uploadID) {
+			h.streamEvents(c, job.ID)
+			return
+		}
+	}
+
+	respondServiceError(c, errors.ErrJobNotFound, http.StatusNotFound)
+}
+
+// streamEvents reenvía los eventos de un trabajo al cliente
+func (h *JobHandler) streamEvents(c *gin.Context, jobID string) {
+	lastEventID, _ := strconv.Atoi(c.GetHeader("Last-Event-ID"))
+
+	history, events, unsubscribe, err := h.jobService.Subscribe(c.Request.Context(), jobID, lastEventID)
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	finished := false
+	send := func(event models.JobEvent) {
+		c.Render(-1, sse.Event{
+			Id:    strconv.Itoa(event.ID),
+			Event: event.Type,
+			Data:  event,
+		})
+		lastEventID = event.ID
+		finished = finished || event.IsTerminal()
+	}
+
+	for _, event := range history {
+		send(event)
+	}
+	c.Writer.Flush()
+
+	if finished {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// El trabajo terminó: enviar los eventos que se hayan descartado
+				// por el buffer, incluido el evento final
+				missed, _, _, err := h.jobService.Subscribe(c.Request.Context(), jobID, lastEventID)
+				if err == nil {
+					for _, event := range missed {
+						send(event)
+					}
+				}
+				return false
+			}
+			send(event)
+			return !finished
+		case <-heartbeat.C:
+			// Comentario SSE para mantener viva la conexión a través de proxies
+			fmt.Fprint(w, ": ping\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// Funciones auxiliares
+
+// sseHeartbeatInterval intervalo entre comentarios de keep-alive en SSE
+const sseHeartbeatInterval = 15 * time.Second
+
+// jobProcessesUpload indica si el trabajo procesa el upload indicado
+func jobProcessesUpload(job *models.Job, uploadID string) bool {
+	if job.Metadata["upload_id"] == uploadID {
+		return true
+	}
+	for _, id := range strings.Split(job.Metadata["upload_ids"], ",") {
+		if id == uploadID {
+			return true
+		}
+	}
+	return false
+}
+
+// respondWithJob responde 202 con el trabajo creado. Si la petición incluye
+// wait=true, espera a que el trabajo termine y responde con su resultado; si
+// el cliente se desconecta o se cancela la petición durante la espera, el
+// trabajo se cancela.
+func respondWithJob(c *gin.Context, jobService services.JobService, job *models.Job, successMessage string, extra gin.H) {
+	if wait, _ := strconv.ParseBool(c.Query("wait")); !wait {
+		data := gin.H{
+			"job":        job,
+			"status_url": "/api/jobs/" + job.ID,
+		}
+		for key, value := range extra {
+			data[key] = value
+		}
+		response.Accepted(c, "Procesamiento iniciado", data)
+		return
+	}
+
+	job, err := jobService.Wait(c.Request.Context(), job.ID)
+	if err != nil {
+		// El cliente se desconectó o el servidor se está apagando: nadie
+		// esperará el resultado
+		slog.WarnContext(c.Request.Context(), "Espera del trabajo interrumpida, se cancela", "job_id", job.ID, "error", err)
+		jobService.Cancel(c.Request.Context(), job.ID)
+		respondServiceError(c, errors.NewContextError(err), http.StatusConflict)
+		return
+	}
+
+	switch {
+	case job.Status == models.JobStatusCompleted:
+		response.Success(c, successMessage, job.Result)
+	case job.Status == models.JobStatusCancelled:
+		response.ErrorWithCode(c, http.StatusConflict, errors.ErrOperationCancelled.Code, job.Error)
+	case job.Error == errors.ErrOperationTimeout.Message:
+		response.ErrorWithCode(c, http.StatusGatewayTimeout, errors.ErrOperationTimeout.Code, job.Error)
+	default:
+		response.Error(c, http.StatusInternalServerError, job.Error)
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"client-data-compiler/internal/services"
+	"client-data-compiler/pkg/response"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RetentionHandler struct {
+	retentionService services.RetentionService
+}
+
+func NewRetentionHandler(retentionService services.RetentionService) *RetentionHandler {
+	return &RetentionHandler{
+		retentionService: retentionService,
+	}
+}
+
+// Report obtiene, sin eliminar nada, los archivos del inquilino que la
+// próxima aplicación de la política de retención eliminaría
+func (h *RetentionHandler) Report(c *gin.Context) {
+	report, err := h.retentionService.Report(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.Success(c, "Informe de retención obtenido", gin.H{"report": report})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/services"
+	"client-data-compiler/pkg/response"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SavedFilterHandler struct {
+	savedFilterService services.SavedFilterService
+}
+
+func NewSavedFilterHandler(savedFilterService services.SavedFilterService) *SavedFilterHandler {
+	return &SavedFilterHandler{
+		savedFilterService: savedFilterService,
+	}
+}
+
+// savedFilterRequest cuerpo de la petición para crear o actualizar un filtro
+type savedFilterRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Filter      string `json:"filter" binding:"required"`
+	Sort        string `json:"sort"`
+}
+
+func (r *savedFilterRequest) toModel() *models.SavedFilter {
+	return &models.SavedFilter{
+		Name:        r.Name,
+		Description: r.Description,
+		Filter:      r.Filter,
+		Sort:        r.Sort,
+	}
+}
+
+// ListFilters obtiene los filtros guardados
+func (h *SavedFilterHandler) ListFilters(c *gin.Context) {
+	filters, err := h.savedFilterService.ListFilters(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.Success(c, "Lista de filtros obtenida", gin.H{
+		"filters": filters,
+		"total":   len(filters),
+	})
+}
+
+// GetFilter obtiene un filtro guardado
+func (h *SavedFilterHandler) GetFilter(c *gin.Context) {
+	filter, err := h.savedFilterService.GetFilter(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.Success(c, "Filtro encontrado", gin.H{"filter": filter})
+}
+
+// CreateFilter guarda un nuevo filtro con nombre
+func (h *SavedFilterHandler) CreateFilter(c *gin.Context) {
+	var req savedFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Datos inválidos: "+err.Error())
+		return
+	}
+
+	filter, err := h.savedFilterService.CreateFilter(c.Request.Context(), req.toModel())
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	response.Created(c, "Filtro guardado exitosamente", gin.H{"filter": filter})
+}
+
+// UpdateFilter reemplaza la definición de un filtro guardado
+func (h *SavedFilterHandler) UpdateFilter(c *gin.Context) {
+	var req savedFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Datos inválidos: "+err.Error())
+		return
+	}
+
+	filter, err := h.savedFilterService.UpdateFilter(c.Request.Context(), c.Param("name"), req.toModel())
+	if err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	response.Success(c, "Filtro actualizado exitosamente", gin.H{"filter": filter})
+}
+
+// DeleteFilter elimina un filtro guardado
+func (h *SavedFilterHandler) DeleteFilter(c *gin.Context) {
+	name := c.Param("name")
+
+	if err := h.savedFilterService.DeleteFilter(c.Request.Context(), name); err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.Success(c, "Filtro eliminado exitosamente", gin.H{"name": name})
+}
@@ -1,308 +1,472 @@
 package handlers
 
 import (
+	"client-data-compiler/internal/config"
+	"client-data-compiler/internal/domain/errors"
 	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/middleware"
 	"client-data-compiler/internal/services"
+	"client-data-compiler/internal/storage"
+	"client-data-compiler/internal/utils"
 	"client-data-compiler/pkg/response"
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UploadHandler struct {
 	clientService services.ClientService
+	uploadService services.UploadService
+	jobService    services.JobService
+	timeouts      config.Timeouts
+	limits        config.Limits
 }
 
-func NewUploadHandler(clientService services.ClientService) *UploadHandler {
+func NewUploadHandler(clientService services.ClientService, uploadService services.UploadService, jobService services.JobService, timeouts config.Timeouts, limits config.Limits) *UploadHandler {
 	return &UploadHandler{
 		clientService: clientService,
+		uploadService: uploadService,
+		jobService:    jobService,
+		timeouts:      timeouts,
+		limits:        limits,
 	}
 }
 
-// UploadExcel maneja la subida de archivos Excel
+// UploadExcel maneja la subida de archivos Excel. El archivo se guarda durante
+// la petición y se procesa en un trabajo en segundo plano; con wait=true se
+// espera a que el trabajo termine y se responde con su resultado.
 func (h *UploadHandler) UploadExcel(c *gin.Context) {
-	log.Printf("Iniciando subida de archivo...")
-
 	// Obtener archivo del formulario
 	file, err := c.FormFile("file")
+	if middleware.IsRequestTooLarge(err) {
+		response.PayloadTooLarge(c, errors.ErrFileTooLarge.Code, h.fileTooLargeMessage())
+		return
+	}
 	if err != nil {
-		log.Printf("Error obteniendo archivo del formulario: %v", err)
+		slog.WarnContext(c.Request.Context(), "Formulario de subida inválido", "error", err)
 		response.Error(c, http.StatusBadRequest, "No se proporcionó un archivo válido")
 		return
 	}
+	defer removeMultipartFiles(c.Request.MultipartForm)
 
-	log.Printf("Archivo recibido: %s, tamaño: %d bytes", file.Filename, file.Size)
+	slog.DebugContext(c.Request.Context(), "Archivo recibido", "filename", file.Filename, "size", file.Size)
+
+	// Validar dataset destino
+	dataset := datasetFromRequest(c)
+	if err := services.ValidateDatasetName(dataset); err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
+		return
+	}
 
 	// Validar que el archivo no esté vacío
 	if file.Size == 0 {
-		log.Printf("Archivo vacío recibido")
+		slog.WarnContext(c.Request.Context(), "Archivo vacío", "filename", file.Filename)
 		response.Error(c, http.StatusBadRequest, "El archivo está vacío")
 		return
 	}
 
-	// Validar extensión del archivo
-	if !strings.HasSuffix(strings.ToLower(file.Filename), ".xlsx") {
-		log.Printf("Extensión de archivo inválida: %s", file.Filename)
+	// Validar extensión y tipo declarado del archivo (el contenido se
+	// verifica al guardarlo)
+	if !isExcelUpload(file) {
+		slog.WarnContext(c.Request.Context(), "Archivo con extensión o tipo inválido", "filename", file.Filename, "content_type", file.Header.Get("Content-Type"))
 		response.Error(c, http.StatusBadRequest, "Solo se permiten archivos Excel (.xlsx)")
 		return
 	}
 
-	// Validar tamaño del archivo (máximo 32MB)
-	maxSize := int64(32 << 20) // 32 MB
-	if file.Size > maxSize {
-		log.Printf("Archivo demasiado grande: %d bytes", file.Size)
-		response.Error(c, http.StatusBadRequest, "El archivo es demasiado grande. Tamaño máximo: 32MB")
+	// Validar tamaño del archivo
+	if file.Size > int64(h.limits.MaxUploadSize) {
+		slog.WarnContext(c.Request.Context(), "Archivo demasiado grande", "filename", file.Filename, "size", file.Size)
+		response.PayloadTooLarge(c, errors.ErrFileTooLarge.Code, h.fileTooLargeMessage())
 		return
 	}
 
-	// Crear directorio uploads si no existe
-	uploadsDir := "uploads"
-	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
-		log.Printf("Error creando directorio uploads: %v", err)
-		response.Error(c, http.StatusInternalServerError, "Error del servidor")
+	force := forceFromRequest(c)
+
+	// Guardar y registrar archivo en el servidor (salvo que ya se haya procesado)
+	upload, duplicateOf, err := h.saveUploadedFile(c, file, dataset, force)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error guardando archivo", "filename", file.Filename, "error", err)
+		if appErr, ok := err.(*errors.AppError); ok {
+			respondServiceError(c, appErr, http.StatusInternalServerError)
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, "Error guardando archivo: "+err.Error())
 		return
 	}
 
-	// Generar nombre único para el archivo
-	timestamp := time.Now().Format("20060102_150405")
-	filename := fmt.Sprintf("%s_%s", timestamp, file.Filename)
-
-	// Limpiar nombre del archivo
-	filename = sanitizeFilename(filename)
-
-	// Ruta de destino
-	uploadPath := filepath.Join(uploadsDir, filename)
-
-	log.Printf("Guardando archivo en: %s", uploadPath)
-
-	// Guardar archivo en el servidor
-	if err := c.SaveUploadedFile(file, uploadPath); err != nil {
-		log.Printf("Error guardando archivo: %v", err)
-		response.Error(c, http.StatusInternalServerError, "Error guardando archivo: "+err.Error())
+	// Archivo idéntico ya procesado: devolver el resultado previo
+	if upload == nil {
+		slog.InfoContext(c.Request.Context(), "Archivo idéntico a un upload anterior, se omite la importación", "filename", file.Filename, "duplicate_of", duplicateOf.ID)
+		response.Success(c, "El archivo ya fue procesado anteriormente. Use force=true para volver a importarlo", gin.H{
+			"dataset":         duplicateOf.Dataset,
+			"filename":        file.Filename,
+			"uploaded_file":   duplicateOf.StoredName,
+			"duplicate":       true,
+			"upload":          duplicateOf,
+			"total_clients":   duplicateOf.TotalRows,
+			"valid_clients":   duplicateOf.ValidCount,
+			"invalid_clients": duplicateOf.InvalidCount,
+		})
 		return
 	}
 
-	log.Printf("Archivo guardado exitosamente, procesando...")
+	slog.InfoContext(c.Request.Context(), "Archivo guardado", "filename", file.Filename, "upload_id", upload.ID, "dataset", dataset)
 
-	// Cargar y procesar el archivo Excel
-	clients, err := h.clientService.LoadClientsFromExcel(uploadPath)
-	if err != nil {
-		log.Printf("Error procesando archivo Excel: %v", err)
-		// Eliminar archivo si hay error en el procesamiento
-		os.Remove(uploadPath)
-		response.Error(c, http.StatusInternalServerError, fmt.Sprintf("Error procesando archivo: %v", err))
-		return
+	// Cargar y procesar el archivo Excel en segundo plano (si falla, el archivo
+	// se elimina). La subida cuenta como en curso hasta que termine el trabajo.
+	metadata := map[string]string{
+		"dataset":   dataset,
+		"upload_id": upload.ID,
+		"filename":  file.Filename,
 	}
+	releaseSlot := middleware.HoldUploadSlot(c)
+	job := h.jobService.Start(c.Request.Context(), models.JobTypeImport, metadata, h.timeouts.Import, func(ctx context.Context, progress services.ProgressReporter) (interface{}, error) {
+		defer releaseSlot()
+		progress.Stage(models.StageFileSaved)
 
-	log.Printf("Archivo procesado exitosamente: %d clientes cargados", len(clients))
+		upload, preview, err := h.uploadService.ProcessUpload(ctx, upload, progress)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error procesando archivo Excel", "upload_id", upload.ID, "error", err)
+			return gin.H{"upload": upload}, fmt.Errorf("Error procesando archivo: %v", err)
+		}
 
-	// Obtener estadísticas
-	stats, _ := h.clientService.GetStats()
+		slog.InfoContext(ctx, "Archivo procesado", "upload_id", upload.ID, "clients", upload.TotalRows)
+
+		result := gin.H{
+			"dataset":         dataset,
+			"filename":        file.Filename,
+			"uploaded_file":   upload.StoredName,
+			"duplicate":       duplicateOf != nil,
+			"upload":          upload,
+			"timings":         upload.Timings,
+			"total_clients":   upload.TotalRows,
+			"valid_clients":   upload.ValidCount,
+			"invalid_clients": upload.InvalidCount,
+			"preview":         preview,
+		}
 
-	// Preparar respuesta
-	responseData := gin.H{
-		"filename":        file.Filename,
-		"uploaded_file":   filename,
-		"total_clients":   len(clients),
-		"valid_clients":   stats.Valid,
-		"invalid_clients": stats.Invalid,
-		"stats":           stats,
-		"preview":         getPreviewClients(clients, 5), // Mostrar primeros 5 clientes
-	}
+		// Obtener estadísticas del dataset. La importación ya quedó registrada,
+		// así que si el dataset se eliminó o vació mientras tanto solo se
+		// omiten las estadísticas.
+		stats, err := h.clientService.GetStats(ctx, dataset, nil)
+		if err != nil {
+			slog.WarnContext(ctx, "No se pudieron obtener las estadísticas del dataset", "upload_id", upload.ID, "dataset", dataset, "error", err)
+		} else {
+			result["stats"] = stats
+		}
 
-	log.Printf("Respuesta preparada exitosamente")
-	response.Success(c, "Archivo Excel cargado y procesado exitosamente", responseData)
+		return result, nil
+	})
+
+	respondWithJob(c, h.jobService, job, "Archivo Excel cargado y procesado exitosamente", gin.H{
+		"upload": upload,
+	})
 }
 
-// UploadMultiple maneja la subida de múltiples archivos Excel
+// UploadMultiple maneja la subida de múltiples archivos Excel. Los archivos se
+// guardan durante la petición y se procesan en un único trabajo en segundo plano.
 func (h *UploadHandler) UploadMultiple(c *gin.Context) {
-	log.Printf("Iniciando subida múltiple de archivos...")
-
 	form, err := c.MultipartForm()
+	if middleware.IsRequestTooLarge(err) {
+		response.PayloadTooLarge(c, "REQUEST_TOO_LARGE", fmt.Sprintf(
+			"La petición es demasiado grande. Se admiten hasta %d archivos de %s como máximo cada uno",
+			h.limits.MaxUploadFiles, h.limits.MaxUploadSize))
+		return
+	}
 	if err != nil {
-		log.Printf("Error procesando formulario múltiple: %v", err)
+		slog.WarnContext(c.Request.Context(), "Formulario de subida múltiple inválido", "error", err)
 		response.Error(c, http.StatusBadRequest, "Error procesando formulario: "+err.Error())
 		return
 	}
+	defer removeMultipartFiles(form)
 
 	files := form.File["files"]
 	if len(files) == 0 {
-		log.Printf("No se proporcionaron archivos")
 		response.Error(c, http.StatusBadRequest, "No se proporcionaron archivos")
 		return
 	}
 
-	log.Printf("Recibidos %d archivos para procesar", len(files))
+	if len(files) > h.limits.MaxUploadFiles {
+		response.Error(c, http.StatusBadRequest, fmt.Sprintf("Se permiten como máximo %d archivos por subida", h.limits.MaxUploadFiles))
+		return
+	}
 
-	var results []gin.H
-	var totalClients int
-	var totalValid int
-	var totalInvalid int
+	slog.DebugContext(c.Request.Context(), "Archivos recibidos", "files", len(files))
 
-	// Crear directorio uploads si no existe
-	uploadsDir := "uploads"
-	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
-		log.Printf("Error creando directorio uploads: %v", err)
-		response.Error(c, http.StatusInternalServerError, "Error del servidor")
+	// Validar dataset destino
+	dataset := datasetFromRequest(c)
+	if err := services.ValidateDatasetName(dataset); err != nil {
+		respondServiceError(c, err, http.StatusBadRequest)
 		return
 	}
 
-	// Procesar cada archivo
-	for i, file := range files {
-		log.Printf("Procesando archivo %d/%d: %s", i+1, len(files), file.Filename)
+	force := forceFromRequest(c)
 
+	// Resultado por archivo, en el mismo orden en que se recibieron
+	results := make([]gin.H, len(files))
+
+	// Archivos guardados pendientes de procesar
+	type pendingUpload struct {
+		index       int
+		filename    string
+		upload      *models.Upload
+		duplicateOf *models.Upload
+	}
+	var pending []pendingUpload
+
+	// Guardar cada archivo
+	for i, file := range files {
 		// Validar archivo
-		if !strings.HasSuffix(strings.ToLower(file.Filename), ".xlsx") {
-			log.Printf("Archivo %s tiene extensión inválida", file.Filename)
-			results = append(results, gin.H{
+		if !isExcelUpload(file) {
+			slog.WarnContext(c.Request.Context(), "Archivo con extensión o tipo inválido", "filename", file.Filename, "content_type", file.Header.Get("Content-Type"))
+			results[i] = gin.H{
 				"filename": file.Filename,
 				"status":   "error",
 				"message":  "Solo se permiten archivos Excel (.xlsx)",
-			})
+			}
 			continue
 		}
 
 		if file.Size == 0 {
-			log.Printf("Archivo %s está vacío", file.Filename)
-			results = append(results, gin.H{
+			slog.WarnContext(c.Request.Context(), "Archivo vacío", "filename", file.Filename)
+			results[i] = gin.H{
 				"filename": file.Filename,
 				"status":   "error",
 				"message":  "El archivo está vacío",
-			})
+			}
 			continue
 		}
 
-		// Generar nombre único
-		timestamp := time.Now().Format("20060102_150405")
-		filename := fmt.Sprintf("%s_%s", timestamp, file.Filename)
-		filename = sanitizeFilename(filename)
-		uploadPath := filepath.Join(uploadsDir, filename)
-
-		// Guardar archivo
-		if err := c.SaveUploadedFile(file, uploadPath); err != nil {
-			log.Printf("Error guardando archivo %s: %v", file.Filename, err)
-			results = append(results, gin.H{
+		if file.Size > int64(h.limits.MaxUploadSize) {
+			slog.WarnContext(c.Request.Context(), "Archivo demasiado grande", "filename", file.Filename, "size", file.Size)
+			results[i] = gin.H{
 				"filename": file.Filename,
 				"status":   "error",
-				"message":  "Error guardando archivo: " + err.Error(),
-			})
+				"code":     errors.ErrFileTooLarge.Code,
+				"message":  h.fileTooLargeMessage(),
+			}
 			continue
 		}
 
-		// Procesar archivo
-		clients, err := h.clientService.LoadClientsFromExcel(uploadPath)
+		// Guardar y registrar archivo
+		upload, duplicateOf, err := h.saveUploadedFile(c, file, dataset, force)
 		if err != nil {
-			log.Printf("Error procesando archivo %s: %v", file.Filename, err)
-			os.Remove(uploadPath)
-			results = append(results, gin.H{
+			slog.ErrorContext(c.Request.Context(), "Error guardando archivo", "filename", file.Filename, "error", err)
+			results[i] = gin.H{
 				"filename": file.Filename,
 				"status":   "error",
-				"message":  err.Error(),
-			})
+				"message":  "Error guardando archivo: " + err.Error(),
+			}
+			if appErr, ok := err.(*errors.AppError); ok {
+				results[i]["code"] = appErr.Code
+			}
 			continue
 		}
 
-		// Obtener estadísticas del archivo actual
-		stats, _ := h.clientService.GetStats()
+		// Archivo idéntico ya procesado
+		if upload == nil {
+			slog.InfoContext(c.Request.Context(), "Archivo idéntico a un upload anterior, se omite la importación", "filename", file.Filename, "duplicate_of", duplicateOf.ID)
+			results[i] = gin.H{
+				"filename":  file.Filename,
+				"upload_id": duplicateOf.ID,
+				"status":    "duplicate",
+				"message":   "El archivo ya fue procesado anteriormente. Use force=true para volver a importarlo",
+			}
+			continue
+		}
 
-		results = append(results, gin.H{
-			"filename":      file.Filename,
-			"status":        "success",
-			"total_clients": len(clients),
-			"valid":         stats.Valid,
-			"invalid":       stats.Invalid,
+		results[i] = gin.H{
+			"filename":  file.Filename,
+			"upload_id": upload.ID,
+			"status":    "pending",
+		}
+		pending = append(pending, pendingUpload{
+			index:       i,
+			filename:    file.Filename,
+			upload:      upload,
+			duplicateOf: duplicateOf,
 		})
+	}
 
-		totalClients += len(clients)
-		totalValid += stats.Valid
-		totalInvalid += stats.Invalid
+	// Sin archivos por procesar: responder de inmediato
+	if len(pending) == 0 {
+		response.Success(c, "Procesamiento de archivos completado", gin.H{
+			"dataset":         dataset,
+			"files_processed": len(files),
+			"results":         results,
+			"total_clients":   0,
+			"total_valid":     0,
+			"total_invalid":   0,
+		})
+		return
+	}
 
-		log.Printf("Archivo %s procesado: %d clientes (%d válidos, %d inválidos)",
-			file.Filename, len(clients), stats.Valid, stats.Invalid)
+	uploadIDs := make([]string, 0, len(pending))
+	for _, p := range pending {
+		uploadIDs = append(uploadIDs, p.upload.ID)
 	}
 
-	responseData := gin.H{
-		"files_processed": len(files),
-		"results":         results,
-		"total_clients":   totalClients,
-		"total_valid":     totalValid,
-		"total_invalid":   totalInvalid,
+	metadata := map[string]string{
+		"dataset":    dataset,
+		"upload_ids": strings.Join(uploadIDs, ","),
 	}
+	releaseSlot := middleware.HoldUploadSlot(c)
+	job := h.jobService.Start(c.Request.Context(), models.JobTypeImport, metadata, h.timeouts.Import, func(ctx context.Context, progress services.ProgressReporter) (interface{}, error) {
+		defer releaseSlot()
+		progress.Stage(models.StageFileSaved)
+
+		jobResults := make([]gin.H, len(results))
+		copy(jobResults, results)
+
+		var totalClients int
+		var totalValid int
+		var totalInvalid int
+
+		// Procesar los archivos (se leen en paralelo y se almacenan en orden)
+		uploads := make([]*models.Upload, len(pending))
+		for i, p := range pending {
+			uploads[i] = p.upload
+		}
+		processed := h.uploadService.ProcessUploads(ctx, uploads, progress)
+
+		for i, p := range pending {
+			upload, err := processed[i].Upload, processed[i].Err
+			if err != nil {
+				slog.ErrorContext(ctx, "Error procesando archivo Excel", "filename", p.filename, "upload_id", upload.ID, "error", err)
+				jobResults[p.index] = gin.H{
+					"filename":  p.filename,
+					"upload_id": upload.ID,
+					"status":    "error",
+					"message":   err.Error(),
+					"timings":   upload.Timings,
+				}
+				continue
+			}
+
+			jobResults[p.index] = gin.H{
+				"filename":      p.filename,
+				"upload_id":     upload.ID,
+				"duplicate":     p.duplicateOf != nil,
+				"status":        "success",
+				"total_clients": upload.TotalRows,
+				"valid":         upload.ValidCount,
+				"invalid":       upload.InvalidCount,
+				"timings":       upload.Timings,
+			}
+
+			totalClients += upload.TotalRows
+			totalValid += upload.ValidCount
+			totalInvalid += upload.InvalidCount
+
+			slog.InfoContext(ctx, "Archivo procesado", "filename", p.filename, "upload_id", upload.ID,
+				"clients", upload.TotalRows, "valid", upload.ValidCount, "invalid", upload.InvalidCount)
+		}
+
+		if ctx.Err() != nil {
+			return gin.H{"results": jobResults}, errors.NewContextError(ctx.Err())
+		}
+
+		slog.InfoContext(ctx, "Procesamiento múltiple completado", "files", len(files), "clients", totalClients)
 
-	log.Printf("Procesamiento múltiple completado: %d archivos procesados", len(files))
-	response.Success(c, "Procesamiento de archivos completado", responseData)
+		return gin.H{
+			"dataset":         dataset,
+			"files_processed": len(files),
+			"results":         jobResults,
+			"total_clients":   totalClients,
+			"total_valid":     totalValid,
+			"total_invalid":   totalInvalid,
+		}, nil
+	})
+
+	respondWithJob(c, h.jobService, job, "Procesamiento de archivos completado", gin.H{
+		"results": results,
+	})
 }
 
-// DownloadTemplate descarga una plantilla de Excel con la estructura correcta
+// DownloadTemplate descarga una plantilla de Excel con la estructura correcta.
+// Si el inquilino tiene una plantilla propia en templates/tenants/<inquilino>
+// se usa esa; si no, la plantilla común.
 func (h *UploadHandler) DownloadTemplate(c *gin.Context) {
-	// Crear directorio templates si no existe
-	templatesDir := "templates"
-	if err := os.MkdirAll(templatesDir, 0755); err != nil {
-		log.Printf("Error creando directorio templates: %v", err)
-		response.Error(c, http.StatusInternalServerError, "Error del servidor")
+	template, err := h.uploadService.GetTemplate(c.Request.Context())
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error obteniendo plantilla", "error", err)
+		respondServiceError(c, err, http.StatusInternalServerError)
 		return
 	}
 
-	templatePath := filepath.Join(templatesDir, "plantilla_clientes.xlsx")
-
-	// Si no existe la plantilla, crear una básica
-	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-		if err := h.createTemplate(templatePath); err != nil {
-			log.Printf("Error creando plantilla: %v", err)
-			response.Error(c, http.StatusInternalServerError, "Error creando plantilla: "+err.Error())
-			return
-		}
-	}
+	serveExcelFile(c, template, "plantilla_clientes.xlsx")
+}
 
-	// Verificar que el archivo existe
-	if _, err := os.Stat(templatePath); os.IsNotExist(err) {
-		response.Error(c, http.StatusNotFound, "Plantilla no encontrada")
+// DownloadFile descarga el archivo de un upload del inquilino por el ID del
+// upload, con su nombre original
+func (h *UploadHandler) DownloadFile(c *gin.Context) {
+	upload, file, err := h.uploadService.GetUploadFile(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
 		return
 	}
 
-	// Establecer headers para descarga
-	c.Header("Content-Description", "File Transfer")
-	c.Header("Content-Transfer-Encoding", "binary")
-	c.Header("Content-Disposition", "attachment; filename=plantilla_clientes.xlsx")
-	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-
-	// Servir archivo
-	c.File(templatePath)
+	serveExcelFile(c, file, utils.SanitizeFilename(upload.OriginalName))
 }
 
-// GetUploadedFiles obtiene la lista de archivos subidos
+// GetUploadedFiles obtiene la lista de archivos subidos por el inquilino
 func (h *UploadHandler) GetUploadedFiles(c *gin.Context) {
-	uploadsDir := "uploads"
-
-	files, err := os.ReadDir(uploadsDir)
+	files, err := h.uploadService.ListFiles(c.Request.Context())
 	if err != nil {
-		log.Printf("Error leyendo directorio uploads: %v", err)
+		slog.ErrorContext(c.Request.Context(), "Error leyendo archivos subidos", "error", err)
 		response.Error(c, http.StatusInternalServerError, "Error leyendo directorio de archivos")
 		return
 	}
 
+	// Asociar cada archivo con los metadatos de su upload, si existen
+	uploads, err := h.uploadService.ListUploads(c.Request.Context())
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error leyendo registro de uploads", "error", err)
+		response.Error(c, http.StatusInternalServerError, "Error leyendo registro de archivos")
+		return
+	}
+
+	uploadsByName := make(map[string]*models.Upload, len(uploads))
+	for _, upload := range uploads {
+		uploadsByName[upload.StoredName] = upload
+	}
+
 	var fileList []gin.H
 	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(strings.ToLower(file.Name()), ".xlsx") {
-			info, _ := file.Info()
-			fileList = append(fileList, gin.H{
-				"name":          file.Name(),
-				"size":          info.Size(),
-				"modified_date": info.ModTime(),
-				"download_url":  "/files/" + file.Name(),
-			})
+		name := path.Base(file.Key)
+		if strings.HasSuffix(strings.ToLower(name), ".xlsx") {
+			entry := gin.H{
+				"name":          name,
+				"size":          file.Size,
+				"modified_date": file.ModTime,
+				"upload":        uploadsByName[name],
+			}
+			// Solo los archivos registrados pueden descargarse
+			if upload, exists := uploadsByName[name]; exists {
+				entry["download_url"] = "/api/files/" + upload.ID
+			}
+			fileList = append(fileList, entry)
 		}
 	}
 
+	storage, err := h.uploadService.StorageUsage(c.Request.Context())
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error calculando espacio utilizado", "error", err)
+	}
+
 	response.Success(c, "Lista de archivos obtenida", gin.H{
-		"files": fileList,
-		"total": len(fileList),
+		"files":   fileList,
+		"total":   len(fileList),
+		"storage": storage,
 	})
 }
 
@@ -314,93 +478,205 @@ func (h *UploadHandler) DeleteUploadedFile(c *gin.Context) {
 		return
 	}
 
-	// Sanitizar nombre del archivo
-	filename = sanitizeFilename(filename)
-	filePath := filepath.Join("uploads", filename)
+	// Si el archivo pertenece a un upload registrado, eliminar también su registro
+	if upload, err := h.findUpload(c.Request.Context(), filename); err == nil {
+		if err := h.uploadService.DeleteUpload(c.Request.Context(), upload.ID); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Error eliminando upload", "upload_id", upload.ID, "error", err)
+			respondServiceError(c, err, http.StatusInternalServerError)
+			return
+		}
 
-	// Verificar que el archivo existe
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		response.Error(c, http.StatusNotFound, "Archivo no encontrado")
+		response.Success(c, "Archivo eliminado exitosamente", gin.H{
+			"filename":  upload.StoredName,
+			"upload_id": upload.ID,
+		})
 		return
 	}
 
-	// Eliminar archivo
-	if err := os.Remove(filePath); err != nil {
-		log.Printf("Error eliminando archivo %s: %v", filePath, err)
-		response.Error(c, http.StatusInternalServerError, "Error eliminando archivo: "+err.Error())
+	if err := h.uploadService.DeleteFile(c.Request.Context(), filename); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error eliminando archivo", "filename", filename, "error", err)
+		respondServiceError(c, err, http.StatusInternalServerError)
 		return
 	}
 
 	response.Success(c, "Archivo eliminado exitosamente", gin.H{
-		"filename": filename,
+		"filename": utils.SanitizeFilename(filename),
 	})
 }
 
+// ListUploads obtiene el registro de uploads con su resultado de procesamiento
+func (h *UploadHandler) ListUploads(c *gin.Context) {
+	uploads, err := h.uploadService.ListUploads(c.Request.Context())
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.Success(c, "Registro de uploads obtenido", gin.H{
+		"uploads": uploads,
+		"total":   len(uploads),
+	})
+}
+
+// GetUpload obtiene los metadatos de un upload por su ID o nombre almacenado
+func (h *UploadHandler) GetUpload(c *gin.Context) {
+	upload, err := h.findUpload(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	response.Success(c, "Upload encontrado", gin.H{"upload": upload})
+}
+
 // Funciones auxiliares
 
-// sanitizeFilename limpia el nombre del archivo para evitar problemas de seguridad
-func sanitizeFilename(filename string) string {
-	// Reemplazar caracteres problemáticos
-	filename = strings.ReplaceAll(filename, " ", "_")
-	filename = strings.ReplaceAll(filename, "/", "_")
-	filename = strings.ReplaceAll(filename, "\\", "_")
-	filename = strings.ReplaceAll(filename, "..", "_")
-	filename = strings.ReplaceAll(filename, ":", "_")
-	filename = strings.ReplaceAll(filename, "*", "_")
-	filename = strings.ReplaceAll(filename, "?", "_")
-	filename = strings.ReplaceAll(filename, "\"", "_")
-	filename = strings.ReplaceAll(filename, "<", "_")
-	filename = strings.ReplaceAll(filename, ">", "_")
-	filename = strings.ReplaceAll(filename, "|", "_")
-
-	return filename
+// saveUploadedFile guarda el archivo recibido con un nombre único y lo registra.
+// Si los clientes de un upload con idéntico contenido siguen cargados en el
+// dataset, se devuelve en duplicateOf y, sin force, no se registra nada
+// (upload es nil). Si el contenido ya está almacenado por otro upload, el
+// nuevo reutiliza ese archivo.
+func (h *UploadHandler) saveUploadedFile(c *gin.Context, file *multipart.FileHeader, dataset string, force bool) (upload, duplicateOf *models.Upload, err error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer src.Close()
+
+	ctx := c.Request.Context()
+
+	// Calcular el hash antes de guardar para detectar archivos repetidos
+	hash, _, err := utils.HashSHA256(utils.NewContextReader(ctx, src))
+	if ctx.Err() != nil {
+		return nil, nil, errors.NewContextError(ctx.Err())
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+
+	metadata := &models.Upload{
+		OriginalName: file.Filename,
+		Dataset:      dataset,
+		Uploader:     uploaderFromRequest(c),
+		Size:         file.Size,
+	}
+
+	duplicateOf, err = h.uploadService.FindDuplicateUpload(ctx, dataset, hash)
+	if err == nil && !force {
+		return nil, duplicateOf, nil
+	}
+
+	// Reimportación, o el mismo archivo en otro dataset: se reutiliza el
+	// archivo ya almacenado
+	if stored, err := h.uploadService.FindStoredUpload(ctx, hash); err == nil {
+		upload, err := h.uploadService.RegisterReupload(ctx, stored, metadata)
+		return upload, duplicateOf, err
+	}
+
+	// El archivo se almacena con un nombre opaco; el original solo se usa
+	// como nombre de descarga
+	metadata.StoredName = utils.GenerateID() + ".xlsx"
+
+	upload, err = h.uploadService.SaveUpload(ctx, src, metadata)
+	return upload, duplicateOf, err
+}
+
+// removeMultipartFiles elimina los archivos temporales del formulario.
+// net/http solo los elimina del formulario de la petición original, no de
+// las copias con otro contexto que crean los middleware.
+func removeMultipartFiles(form *multipart.Form) {
+	if form == nil {
+		return
+	}
+	if err := form.RemoveAll(); err != nil {
+		slog.Error("Error eliminando los archivos temporales del formulario", "error", err)
+	}
+}
+
+// fileTooLargeMessage mensaje para los archivos que exceden el tamaño máximo
+func (h *UploadHandler) fileTooLargeMessage() string {
+	return fmt.Sprintf("El archivo es demasiado grande. Tamaño máximo: %s", h.limits.MaxUploadSize)
+}
+
+// forceFromRequest indica si se pidió reimportar archivos ya procesados
+func forceFromRequest(c *gin.Context) bool {
+	value := c.Query("force")
+	if value == "" {
+		value = c.PostForm("force")
+	}
+	force, _ := strconv.ParseBool(value)
+	return force
+}
+
+// findUpload busca un upload por ID y, si no existe, por nombre almacenado
+func (h *UploadHandler) findUpload(ctx context.Context, idOrName string) (*models.Upload, error) {
+	if upload, err := h.uploadService.GetUpload(ctx, idOrName); err == nil {
+		return upload, nil
+	}
+	return h.uploadService.GetUploadByStoredName(ctx, idOrName)
+}
+
+// uploaderFromRequest identifica a quien sube el archivo. Si la petición está
+// autenticada se usa el principal, que no puede suplantarse con el formulario.
+func uploaderFromRequest(c *gin.Context) string {
+	if principal, ok := middleware.PrincipalFromContext(c); ok && principal.AuthMethod != models.AuthMethodNone {
+		return principal.Subject
+	}
+	if uploader := c.PostForm("uploader"); uploader != "" {
+		return uploader
+	}
+	if uploader := c.GetHeader("X-Uploader"); uploader != "" {
+		return uploader
+	}
+	return c.ClientIP()
 }
 
-// getPreviewClients obtiene una vista previa de los primeros N clientes
-func getPreviewClients(clients []*models.Client, limit int) []*models.Client {
-	if len(clients) <= limit {
-		return clients
+// xlsxContentType tipo MIME de los libros de Excel
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// excelUploadTypes tipos MIME que los navegadores y clientes declaran para un
+// .xlsx; algunos no lo reconocen y lo envían como zip o binario genérico
+var excelUploadTypes = map[string]bool{
+	xlsxContentType:                true,
+	"application/octet-stream":     true,
+	"application/zip":              true,
+	"application/x-zip-compressed": true,
+}
+
+// isExcelUpload indica si el archivo recibido tiene extensión .xlsx y un tipo
+// MIME declarado compatible
+func isExcelUpload(file *multipart.FileHeader) bool {
+	if !strings.HasSuffix(strings.ToLower(file.Filename), ".xlsx") {
+		return false
 	}
-	return clients[:limit]
+
+	contentType := file.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && excelUploadTypes[mediaType]
 }
 
-// createTemplate crea una plantilla básica de Excel
-func (h *UploadHandler) createTemplate(templatePath string) error {
-	// Crear directorio si no existe
-	dir := filepath.Dir(templatePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
-
-	// Crear clientes de ejemplo para la plantilla
-	sampleClients := []*models.Client{
-		{
-			ID:       1,
-			Clave:    "1001",
-			Nombre:   "Juan Pérez García",
-			Correo:   "juan.perez@gmail.com",
-			Telefono: "9611234567",
-			IsValid:  true,
-		},
-		{
-			ID:       2,
-			Clave:    "1002",
-			Nombre:   "María López Hernández",
-			Correo:   "maria.lopez@hotmail.com",
-			Telefono: "9629876543",
-			IsValid:  true,
-		},
-		{
-			ID:       3,
-			Clave:    "1003",
-			Nombre:   "Carlos Rodríguez Méndez",
-			Correo:   "carlos.rodriguez@yahoo.com",
-			Telefono: "9675551234",
-			IsValid:  true,
-		},
-	}
-
-	// Usar el servicio Excel para crear la plantilla
-	excelService := services.NewExcelService()
-	return excelService.WriteExcelFile(sampleClients, templatePath)
+// serveExcelFile envía un archivo Excel almacenado como descarga con el
+// nombre indicado y lo cierra. nosniff impide que el navegador lo interprete
+// como otro tipo de contenido.
+func serveExcelFile(c *gin.Context, file *storage.Object, filename string) {
+	defer file.Body.Close()
+
+	if !strings.HasSuffix(strings.ToLower(filename), ".xlsx") {
+		filename += ".xlsx"
+	}
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	if !file.ModTime.IsZero() {
+		c.Header("Last-Modified", file.ModTime.UTC().Format(http.TimeFormat))
+	}
+	c.DataFromReader(http.StatusOK, file.Size, xlsxContentType, file.Body, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, filename),
+	})
 }
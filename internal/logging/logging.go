@@ -0,0 +1,124 @@
+// Package logging configura el logging estructurado de la API con log/slog.
+// Los atributos asociados al contexto (p. ej. el ID de la petición) se
+// agregan a cada registro emitido con las funciones *Context de slog, y los
+// datos personales se ocultan.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Formatos de salida admitidos
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ParseLevel interpreta un nivel: debug, info, warn o error
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return 0, fmt.Errorf("nivel de log desconocido '%s' (use debug, info, warn o error)", value)
+	}
+	return level, nil
+}
+
+// New crea un logger que escribe en w con el nivel mínimo y el formato
+// indicados, agregando los atributos del contexto y ocultando los datos
+// personales
+func New(w io.Writer, level slog.Level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, FormatJSON) {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+// contextKey clave de los atributos de log en el contexto
+type contextKey struct{}
+
+// requestIDKey clave del ID de la petición en el contexto
+type requestIDKey struct{}
+
+// With asocia atributos de log a ctx, con los mismos argumentos que
+// slog.Logger.With. Se agregan a los registros emitidos con ctx.
+func With(ctx context.Context, args ...any) context.Context {
+	attrs := append([]slog.Attr(nil), attrsFromContext(ctx)...)
+
+	record := slog.Record{}
+	record.Add(args...)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	return context.WithValue(ctx, contextKey{}, attrs)
+}
+
+// WithRequestID asocia el ID de la petición a ctx, también como atributo
+// request_id de los registros
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	return With(ctx, "request_id", id)
+}
+
+// RequestID obtiene el ID de la petición de ctx, o "" si no tiene
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Inherit asocia a ctx el ID de la petición y los atributos de log de from,
+// p. ej. para que los registros de un trabajo en segundo plano conserven los
+// de la petición que lo inició
+func Inherit(ctx, from context.Context) context.Context {
+	if id := RequestID(from); id != "" {
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+	}
+	if attrs := attrsFromContext(from); len(attrs) > 0 {
+		ctx = context.WithValue(ctx, contextKey{}, attrs)
+	}
+	return ctx
+}
+
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler agrega a cada registro los atributos de su contexto
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if attrs := attrsFromContext(ctx); len(attrs) > 0 {
+			record.AddAttrs(attrs...)
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// redactedKeys atributos con datos personales que nunca se registran completos
+var redactedKeys = map[string]func(string) string{
+	"correo":   RedactEmail,
+	"email":    RedactEmail,
+	"telefono": RedactPhone,
+	"phone":    RedactPhone,
+}
+
+// redactAttr oculta el valor de los atributos con datos personales
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	redact, ok := redactedKeys[strings.ToLower(attr.Key)]
+	if !ok || attr.Value.Kind() != slog.KindString {
+		return attr
+	}
+	return slog.String(attr.Key, redact(attr.Value.String()))
+}
+
+// RedactEmail oculta la parte local de un correo salvo su primer carácter,
+// p. ej. "j***@gmail.com"; el dominio se conserva para diagnosticar errores
+// de validación
+func RedactEmail(email string) string {
+	if email == "" {
+		return ""
+	}
+	local, domain, found := strings.Cut(email, "@")
+	if !found {
+		return "***"
+	}
+	if local == "" {
+		return "***@" + domain
+	}
+	_, size := utf8.DecodeRuneInString(local)
+	return local[:size] + "***@" + domain
+}
+
+// RedactPhone oculta un teléfono salvo sus dos últimos dígitos, p. ej.
+// "********67"
+func RedactPhone(phone string) string {
+	digits := []rune(strings.TrimSpace(phone))
+	if len(digits) <= 2 {
+		return strings.Repeat("*", len(digits))
+	}
+	return strings.Repeat("*", len(digits)-2) + string(digits[len(digits)-2:])
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"client-data-compiler/internal/config"
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/tenant"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// apiKeyAuthenticator autentica con API keys estáticas enviadas en la
+// cabecera X-API-Key o como Authorization: ApiKey <clave>
+type apiKeyAuthenticator struct {
+	// principals principal de cada clave, indexado por el hash de la clave
+	// para no comparar las claves directamente
+	principals map[[sha256.Size]byte]models.Principal
+}
+
+// NewAPIKeyAuthenticator crea un autenticador con las claves indicadas y el
+// principal al que identifica cada una. Las claves sin rol tienen rol viewer
+// y las claves sin inquilino, el inquilino por defecto.
+func NewAPIKeyAuthenticator(keys []config.APIKey) (Authenticator, error) {
+	principals := make(map[[sha256.Size]byte]models.Principal, len(keys))
+	for _, apiKey := range keys {
+		role := models.RoleViewer
+		if apiKey.Role != "" {
+			parsed, err := models.ParseRole(apiKey.Role)
+			if err != nil {
+				return nil, fmt.Errorf("API key %s: %w", apiKey.Name, err)
+			}
+			role = parsed
+		}
+
+		tenantID := tenant.Normalize(apiKey.Tenant)
+		if err := tenant.Validate(tenantID); err != nil {
+			return nil, fmt.Errorf("API key %s: %w", apiKey.Name, err)
+		}
+
+		principals[sha256.Sum256([]byte(apiKey.Key))] = models.Principal{
+			Subject:    apiKey.Name,
+			AuthMethod: models.AuthMethodAPIKey,
+			Role:       role,
+			Tenant:     tenantID,
+		}
+	}
+	return &apiKeyAuthenticator{principals: principals}, nil
+}
+
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (*models.Principal, error) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		scheme, value, found := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "ApiKey") {
+			return nil, ErrNoCredentials
+		}
+		key = strings.TrimSpace(value)
+	}
+
+	principal, exists := a.principals[sha256.Sum256([]byte(key))]
+	if !exists {
+		return nil, errors.New("API key inválida")
+	}
+
+	return &principal, nil
+}
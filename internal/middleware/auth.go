@@ -0,0 +1,112 @@
+// Package middleware contiene los middleware HTTP de la API
+package middleware
+
+import (
+	"client-data-compiler/internal/config"
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/logging"
+	"client-data-compiler/internal/tenant"
+	"client-data-compiler/pkg/response"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey clave del principal autenticado en el contexto de gin
+const principalKey = "auth.principal"
+
+// ErrNoCredentials indica que la petición no trae credenciales del tipo que
+// maneja el autenticador, de modo que puede probarse con el siguiente
+var ErrNoCredentials = errors.New("la petición no incluye credenciales")
+
+// Authenticator identifica al principal de una petición. Devuelve
+// ErrNoCredentials si la petición no trae sus credenciales y cualquier otro
+// error si las trae pero no son válidas.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*models.Principal, error)
+}
+
+// NewAuthenticators crea los autenticadores correspondientes a la
+// configuración: API keys, HS256 y RS256, en ese orden
+func NewAuthenticators(cfg config.Auth) ([]Authenticator, error) {
+	var authenticators []Authenticator
+
+	if len(cfg.APIKeys) > 0 {
+		apiKeys, err := NewAPIKeyAuthenticator(cfg.APIKeys)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, apiKeys)
+	}
+
+	if cfg.JWTSecret != "" || cfg.JWTPublicKeyFile != "" {
+		jwtConfig := JWTConfig{
+			Secret:   []byte(cfg.JWTSecret),
+			Issuer:   cfg.JWTIssuer,
+			Audience: cfg.JWTAudience,
+		}
+
+		if cfg.JWTPublicKeyFile != "" {
+			publicKey, err := LoadRSAPublicKey(cfg.JWTPublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			jwtConfig.PublicKey = publicKey
+		}
+
+		authenticators = append(authenticators, NewJWTAuthenticator(jwtConfig))
+	}
+
+	return authenticators, nil
+}
+
+// Authenticate exige que la petición se autentique con alguno de los
+// autenticadores y deja el principal disponible con PrincipalFromContext. El
+// inquilino del principal se asocia al contexto de la petición.
+func Authenticate(authenticators ...Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, authenticator := range authenticators {
+			principal, err := authenticator.Authenticate(c.Request)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if err != nil {
+				unauthorized(c, err.Error())
+				return
+			}
+
+			setPrincipal(c, principal)
+			c.Next()
+			return
+		}
+
+		unauthorized(c, "Se requieren credenciales: cabecera X-API-Key o Authorization: Bearer <token>")
+	}
+}
+
+// PrincipalFromContext obtiene el principal autenticado de la petición
+func PrincipalFromContext(c *gin.Context) (*models.Principal, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return nil, false
+	}
+	principal, ok := value.(*models.Principal)
+	return principal, ok
+}
+
+// setPrincipal guarda el principal en el contexto de gin y su inquilino y
+// atributos de log en el contexto de la petición, que es el que llega a
+// servicios y repositorios
+func setPrincipal(c *gin.Context, principal *models.Principal) {
+	c.Set(principalKey, principal)
+	ctx := tenant.WithTenant(c.Request.Context(), principal.Tenant)
+	ctx = logging.With(ctx, "subject", principal.Subject, "tenant", principal.Tenant)
+	c.Request = c.Request.WithContext(ctx)
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	response.Unauthorized(c, message)
+	c.Abort()
+}
@@ -0,0 +1,260 @@
+package middleware
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/tenant"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// jwtLeeway tolerancia de reloj al comprobar exp y nbf
+const jwtLeeway = 30 * time.Second
+
+// JWTConfig claves y restricciones para verificar tokens JWT. Solo se aceptan
+// los algoritmos con clave configurada: HS256 con Secret y RS256 con PublicKey.
+type JWTConfig struct {
+	Secret    []byte
+	PublicKey *rsa.PublicKey
+	// Issuer y Audience, si no están vacíos, deben coincidir con iss y aud
+	Issuer   string
+	Audience string
+}
+
+// jwtAuthenticator autentica con tokens JWT enviados como
+// Authorization: Bearer <token>
+type jwtAuthenticator struct {
+	config JWTConfig
+	now    func() time.Time
+}
+
+// NewJWTAuthenticator crea un autenticador de tokens JWT
+func NewJWTAuthenticator(config JWTConfig) Authenticator {
+	return &jwtAuthenticator{config: config, now: time.Now}
+}
+
+// jwtHeader cabecera de un token JWT
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+func (a *jwtAuthenticator) Authenticate(r *http.Request) (*models.Principal, error) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := a.verify(strings.TrimSpace(token))
+	if err != nil {
+		return nil, fmt.Errorf("Token inválido: %v", err)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("Token inválido: falta el claim sub")
+	}
+
+	// El inquilino se toma del claim tenant; sin él, el inquilino por defecto
+	tenantClaim, _ := claims["tenant"].(string)
+	tenantID := tenant.Normalize(tenantClaim)
+	if err := tenant.Validate(tenantID); err != nil {
+		return nil, fmt.Errorf("Token inválido: %v", err)
+	}
+
+	return &models.Principal{
+		Subject:    subject,
+		AuthMethod: models.AuthMethodJWT,
+		Role:       roleFromClaims(claims),
+		Tenant:     tenantID,
+		Claims:     claims,
+	}, nil
+}
+
+// roleFromClaims obtiene el rol del claim role, o el de mayor privilegio del
+// claim roles. Los roles desconocidos se ignoran y, sin ninguno, el rol es viewer.
+func roleFromClaims(claims map[string]interface{}) models.Role {
+	candidates := []interface{}{claims["role"]}
+	if roles, ok := claims["roles"].([]interface{}); ok {
+		candidates = append(candidates, roles...)
+	}
+
+	best := models.RoleViewer
+	for _, candidate := range candidates {
+		name, _ := candidate.(string)
+		if role, err := models.ParseRole(name); err == nil && role.Allows(best) {
+			best = role
+		}
+	}
+
+	return best
+}
+
+// verify comprueba la firma y las fechas del token y devuelve sus claims
+func (a *jwtAuthenticator) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("formato incorrecto")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.New("cabecera ilegible")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("firma ilegible")
+	}
+
+	if err := a.verifySignature(header.Alg, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, errors.New("claims ilegibles")
+	}
+
+	if err := a.verifyClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// verifySignature comprueba la firma con el algoritmo de la cabecera, siempre
+// que tenga clave configurada. Así un token HS256 no puede firmarse con la
+// clave pública RS256.
+func (a *jwtAuthenticator) verifySignature(alg, signingInput string, signature []byte) error {
+	switch {
+	case alg == "HS256" && len(a.config.Secret) > 0:
+		mac := hmac.New(sha256.New, a.config.Secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return errors.New("firma no válida")
+		}
+		return nil
+
+	case alg == "RS256" && a.config.PublicKey != nil:
+		digest := sha256.Sum256([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(a.config.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("firma no válida")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("algoritmo %q no admitido", alg)
+}
+
+// verifyClaims comprueba exp, nbf, iss y aud. exp es obligatorio para que
+// ningún token sea válido indefinidamente.
+func (a *jwtAuthenticator) verifyClaims(claims map[string]interface{}) error {
+	now := a.now()
+
+	exp, ok := claims["exp"]
+	if !ok {
+		return errors.New("falta el claim exp")
+	}
+	seconds, isNumber := exp.(float64)
+	if !isNumber {
+		return errors.New("claim exp inválido")
+	}
+	if now.After(time.Unix(int64(seconds), 0).Add(jwtLeeway)) {
+		return errors.New("el token expiró")
+	}
+
+	if nbf, ok := claims["nbf"]; ok {
+		seconds, isNumber := nbf.(float64)
+		if !isNumber {
+			return errors.New("claim nbf inválido")
+		}
+		if now.Add(jwtLeeway).Before(time.Unix(int64(seconds), 0)) {
+			return errors.New("el token aún no es válido")
+		}
+	}
+
+	if a.config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != a.config.Issuer {
+			return errors.New("emisor no válido")
+		}
+	}
+
+	if a.config.Audience != "" && !hasAudience(claims["aud"], a.config.Audience) {
+		return errors.New("audiencia no válida")
+	}
+
+	return nil
+}
+
+// hasAudience indica si el claim aud, una cadena o una lista, incluye audience
+func hasAudience(aud interface{}, audience string) bool {
+	switch value := aud.(type) {
+	case string:
+		return value == audience
+	case []interface{}:
+		for _, item := range value {
+			if s, ok := item.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeSegment decodifica un segmento base64url con JSON
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// LoadRSAPublicKey lee una clave pública RSA de un archivo PEM en formato
+// PKIX ("PUBLIC KEY"), PKCS#1 ("RSA PUBLIC KEY") o de un certificado
+func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer la clave pública JWT: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("la clave pública JWT %s no está en formato PEM", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("clave pública JWT inválida: %w", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("la clave pública JWT %s no es RSA", path)
+	}
+
+	return rsaKey, nil
+}
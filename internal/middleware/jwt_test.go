@@ -0,0 +1,358 @@
+package middleware
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testSecret = []byte("secreto-de-prueba")
+	testNow    = time.Date(2025, 5, 25, 12, 0, 0, 0, time.UTC)
+)
+
+// testRSAKey clave RSA compartida por las pruebas; generarla es lento
+var testRSAKey = func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// signToken construye un token con la cabecera alg y la firma que devuelve sign
+func signToken(t *testing.T, alg string, claims map[string]interface{}, sign func(signingInput string) []byte) string {
+	t.Helper()
+
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	signingInput := encode(jwtHeader{Alg: alg, Typ: "JWT"}) + "." + encode(claims)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sign(signingInput))
+}
+
+func hs256(secret []byte) func(string) []byte {
+	return func(signingInput string) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		return mac.Sum(nil)
+	}
+}
+
+func rs256(key *rsa.PrivateKey) func(string) []byte {
+	return func(signingInput string) []byte {
+		digest := sha256.Sum256([]byte(signingInput))
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			panic(err)
+		}
+		return signature
+	}
+}
+
+func unsigned(string) []byte { return nil }
+
+// validClaims claims vigentes en testNow, con los cambios de overrides. Un
+// valor nil elimina el claim.
+func validClaims(overrides map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{
+		"sub": "usuario",
+		"iss": "https://auth.example.com",
+		"aud": "client-data-compiler",
+		"exp": testNow.Add(time.Hour).Unix(),
+		"nbf": testNow.Add(-time.Hour).Unix(),
+	}
+	for name, value := range overrides {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestJWTVerify(t *testing.T) {
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&testRSAKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hsOnly := JWTConfig{Secret: testSecret, Issuer: "https://auth.example.com", Audience: "client-data-compiler"}
+	rsOnly := JWTConfig{PublicKey: &testRSAKey.PublicKey, Issuer: "https://auth.example.com", Audience: "client-data-compiler"}
+	both := JWTConfig{Secret: testSecret, PublicKey: &testRSAKey.PublicKey}
+
+	tests := []struct {
+		name    string
+		config  JWTConfig
+		token   func(t *testing.T) string
+		wantErr string
+	}{
+		{
+			name:   "HS256 válido",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				return signToken(t, "HS256", validClaims(nil), hs256(testSecret))
+			},
+		},
+		{
+			name:   "RS256 válido",
+			config: rsOnly,
+			token: func(t *testing.T) string {
+				return signToken(t, "RS256", validClaims(nil), rs256(testRSAKey))
+			},
+		},
+		{
+			name:   "alg none",
+			config: both,
+			token: func(t *testing.T) string {
+				return signToken(t, "none", validClaims(nil), unsigned)
+			},
+			wantErr: "no admitido",
+		},
+		{
+			name:   "alg None",
+			config: both,
+			token: func(t *testing.T) string {
+				return signToken(t, "None", validClaims(nil), unsigned)
+			},
+			wantErr: "no admitido",
+		},
+		{
+			name:   "HS256 firmado con la clave pública RS256",
+			config: rsOnly,
+			token: func(t *testing.T) string {
+				return signToken(t, "HS256", validClaims(nil), hs256(publicKeyDER))
+			},
+			wantErr: "no admitido",
+		},
+		{
+			name:   "HS256 firmado con la clave pública aunque haya secreto",
+			config: both,
+			token: func(t *testing.T) string {
+				return signToken(t, "HS256", validClaims(nil), hs256(publicKeyDER))
+			},
+			wantErr: "firma no válida",
+		},
+		{
+			name:   "RS256 sin clave pública configurada",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				return signToken(t, "RS256", validClaims(nil), rs256(testRSAKey))
+			},
+			wantErr: "no admitido",
+		},
+		{
+			name:   "RS256 con firma HMAC",
+			config: both,
+			token: func(t *testing.T) string {
+				return signToken(t, "RS256", validClaims(nil), hs256(testSecret))
+			},
+			wantErr: "firma no válida",
+		},
+		{
+			name:   "HS256 con otro secreto",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				return signToken(t, "HS256", validClaims(nil), hs256([]byte("otro-secreto")))
+			},
+			wantErr: "firma no válida",
+		},
+		{
+			name:   "RS256 con otra clave",
+			config: rsOnly,
+			token: func(t *testing.T) string {
+				return signToken(t, "RS256", validClaims(nil), rs256(otherKey))
+			},
+			wantErr: "firma no válida",
+		},
+		{
+			name:   "claims modificados tras firmar",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				parts := strings.Split(signToken(t, "HS256", validClaims(nil), hs256(testSecret)), ".")
+				forged := strings.Split(signToken(t, "HS256", validClaims(map[string]interface{}{"role": "admin"}), unsigned), ".")
+				return parts[0] + "." + forged[1] + "." + parts[2]
+			},
+			wantErr: "firma no válida",
+		},
+		{
+			name:    "formato incorrecto",
+			config:  hsOnly,
+			token:   func(t *testing.T) string { return "a.b" },
+			wantErr: "formato incorrecto",
+		},
+		{
+			name:   "expirado",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				claims := validClaims(map[string]interface{}{"exp": testNow.Add(-time.Minute).Unix()})
+				return signToken(t, "HS256", claims, hs256(testSecret))
+			},
+			wantErr: "expiró",
+		},
+		{
+			name:   "expirado dentro de la tolerancia",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				claims := validClaims(map[string]interface{}{"exp": testNow.Add(-jwtLeeway / 2).Unix()})
+				return signToken(t, "HS256", claims, hs256(testSecret))
+			},
+		},
+		{
+			name:   "sin exp",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				return signToken(t, "HS256", validClaims(map[string]interface{}{"exp": nil}), hs256(testSecret))
+			},
+			wantErr: "falta el claim exp",
+		},
+		{
+			name:   "exp no numérico",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				return signToken(t, "HS256", validClaims(map[string]interface{}{"exp": "mañana"}), hs256(testSecret))
+			},
+			wantErr: "claim exp inválido",
+		},
+		{
+			name:   "nbf en el futuro",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				claims := validClaims(map[string]interface{}{"nbf": testNow.Add(time.Minute).Unix()})
+				return signToken(t, "HS256", claims, hs256(testSecret))
+			},
+			wantErr: "aún no es válido",
+		},
+		{
+			name:   "aud como lista",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				claims := validClaims(map[string]interface{}{"aud": []string{"otra-api", "client-data-compiler"}})
+				return signToken(t, "HS256", claims, hs256(testSecret))
+			},
+		},
+		{
+			name:   "aud como lista sin la audiencia",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				claims := validClaims(map[string]interface{}{"aud": []string{"otra-api"}})
+				return signToken(t, "HS256", claims, hs256(testSecret))
+			},
+			wantErr: "audiencia no válida",
+		},
+		{
+			name:   "aud distinta",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				claims := validClaims(map[string]interface{}{"aud": "otra-api"})
+				return signToken(t, "HS256", claims, hs256(testSecret))
+			},
+			wantErr: "audiencia no válida",
+		},
+		{
+			name:   "sin aud",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				return signToken(t, "HS256", validClaims(map[string]interface{}{"aud": nil}), hs256(testSecret))
+			},
+			wantErr: "audiencia no válida",
+		},
+		{
+			name:   "iss distinto",
+			config: hsOnly,
+			token: func(t *testing.T) string {
+				claims := validClaims(map[string]interface{}{"iss": "https://otro.example.com"})
+				return signToken(t, "HS256", claims, hs256(testSecret))
+			},
+			wantErr: "emisor no válido",
+		},
+		{
+			name:   "sin iss ni aud configurados",
+			config: both,
+			token: func(t *testing.T) string {
+				claims := validClaims(map[string]interface{}{"iss": nil, "aud": nil})
+				return signToken(t, "HS256", claims, hs256(testSecret))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &jwtAuthenticator{config: tt.config, now: func() time.Time { return testNow }}
+
+			claims, err := a.verify(tt.token(t))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verify error: %v", err)
+				}
+				if claims["sub"] != "usuario" {
+					t.Errorf("claim sub = %v, se esperaba usuario", claims["sub"])
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("verify no devolvió error, se esperaba %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("verify error %q, se esperaba que contuviera %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRoleFromClaims(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   models.Role
+	}{
+		{name: "sin rol", claims: map[string]interface{}{}, want: models.RoleViewer},
+		{name: "role", claims: map[string]interface{}{"role": "editor"}, want: models.RoleEditor},
+		{name: "role desconocido", claims: map[string]interface{}{"role": "superuser"}, want: models.RoleViewer},
+		{name: "role en mayúsculas", claims: map[string]interface{}{"role": "ADMIN"}, want: models.RoleViewer},
+		{name: "role no es texto", claims: map[string]interface{}{"role": 3.0}, want: models.RoleViewer},
+		{
+			name:   "roles con desconocidos",
+			claims: map[string]interface{}{"roles": []interface{}{"superuser", "editor", 7.0}},
+			want:   models.RoleEditor,
+		},
+		{
+			name:   "el de mayor privilegio entre role y roles",
+			claims: map[string]interface{}{"role": "editor", "roles": []interface{}{"viewer", "admin"}},
+			want:   models.RoleAdmin,
+		},
+		{
+			name:   "roles no es una lista",
+			claims: map[string]interface{}{"roles": "admin"},
+			want:   models.RoleViewer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := roleFromClaims(tt.claims); got != tt.want {
+				t.Errorf("roleFromClaims = %s, se esperaba %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/pkg/response"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bucketIdleTTL tiempo sin peticiones tras el cual se descarta el bucket de
+// un principal; para entonces ya estaría lleno
+const bucketIdleTTL = 10 * time.Minute
+
+// RateLimiter limita las peticiones de cada principal con un token bucket:
+// admite ráfagas de hasta burst peticiones y se recarga a perMinute por minuto
+type RateLimiter struct {
+	rate      float64 // tokens por segundo
+	burst     float64
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// NewRateLimiter crea un limitador de perMinute peticiones por minuto con
+// ráfagas de hasta burst peticiones
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	if burst <= 0 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:      float64(perMinute) / 60,
+		burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+		lastPrune: time.Now(),
+	}
+}
+
+// Allow consume un token de key. Devuelve si la petición se admite, los
+// tokens restantes y, si no se admite, cuánto falta para el siguiente token.
+func (l *RateLimiter) Allow(key string) (bool, int, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key, now)
+	if b.tokens < 1 {
+		return false, 0, l.untilNextToken(b)
+	}
+
+	b.tokens--
+	return true, int(b.tokens), 0
+}
+
+// Wait indica cuánto falta para que key tenga un token, sin consumirlo; cero
+// si ya lo tiene
+func (l *RateLimiter) Wait(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.refill(key, time.Now())
+	if b.tokens < 1 {
+		return l.untilNextToken(b)
+	}
+	return 0
+}
+
+// Middleware aplica el límite a cada petición según su principal, o su IP si
+// no está autenticada. Debe registrarse después de la autenticación.
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	limit := strconv.Itoa(int(l.burst))
+
+	return func(c *gin.Context) {
+		allowed, remaining, retryAfter := l.Allow(clientKey(c))
+
+		c.Header("X-RateLimit-Limit", limit)
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+		if !allowed {
+			response.TooManyRequests(c, "RATE_LIMITED",
+				fmt.Sprintf("Límite de peticiones excedido. Intente de nuevo en %s", retryAfter.Round(time.Second)), retryAfter)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// FailureMiddleware limita por IP las peticiones rechazadas con 401, para que
+// no puedan probarse API keys o tokens por fuerza bruta. Debe registrarse
+// antes de la autenticación: cada 401 consume un token de la IP y, sin
+// tokens, se rechaza cualquier petición de esa IP hasta que se recargue.
+func (l *RateLimiter) FailureMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+
+		if retryAfter := l.Wait(key); retryAfter > 0 {
+			response.TooManyRequests(c, "AUTH_RATE_LIMITED",
+				fmt.Sprintf("Demasiados intentos de autenticación fallidos. Intente de nuevo en %s", retryAfter.Round(time.Second)), retryAfter)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+
+		if c.Writer.Status() == http.StatusUnauthorized {
+			l.Allow(key)
+		}
+	}
+}
+
+// refill obtiene el bucket de key, creándolo lleno si no existe, con los
+// tokens recargados hasta now (requiere el lock)
+func (l *RateLimiter) refill(key string, now time.Time) *bucket {
+	l.pruneIdle(now)
+
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: l.burst, lastSeen: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate)
+	b.lastSeen = now
+	return b
+}
+
+// untilNextToken tiempo que falta para que b tenga un token completo
+func (l *RateLimiter) untilNextToken(b *bucket) time.Duration {
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// pruneIdle descarta los buckets sin actividad reciente (requiere el lock)
+func (l *RateLimiter) pruneIdle(now time.Time) {
+	if now.Sub(l.lastPrune) < bucketIdleTTL {
+		return
+	}
+	l.lastPrune = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > bucketIdleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// clientKey identifica a quien hace la petición: su principal si está
+// autenticado y su IP en caso contrario
+func clientKey(c *gin.Context) string {
+	if principal, ok := PrincipalFromContext(c); ok && principal.AuthMethod != models.AuthMethodNone {
+		return string(principal.AuthMethod) + ":" + principal.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
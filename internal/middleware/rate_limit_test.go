@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newFailureRouter router que rechaza todas las peticiones con 401 tras
+// FailureMiddleware, con los proxies de confianza indicados
+func newFailureRouter(t *testing.T, trustedProxies []string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatal(err)
+	}
+	router.Use(NewRateLimiter(1, 2).FailureMiddleware())
+	router.GET("/", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+	})
+	return router
+}
+
+// failedAttempt hace una petición desde remoteAddr con la cabecera
+// X-Forwarded-For indicada y devuelve el código de estado
+func failedAttempt(router *gin.Engine, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestFailureMiddlewareIgnoresSpoofedForwardedFor(t *testing.T) {
+	router := newFailureRouter(t, nil)
+
+	// Cada intento falsifica otra IP, pero todos vienen de la misma conexión
+	spoofed := []string{"1.1.1.1", "2.2.2.2", "3.3.3.3", "4.4.4.4"}
+	codes := make([]int, len(spoofed))
+	for i, ip := range spoofed {
+		codes[i] = failedAttempt(router, "203.0.113.7:5000", ip)
+	}
+
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("códigos = %v, se esperaba %v", codes, want)
+		}
+	}
+
+	// Otra conexión tiene su propio límite
+	if code := failedAttempt(router, "203.0.113.8:5000", "1.1.1.1"); code != http.StatusUnauthorized {
+		t.Errorf("otra IP = %d, se esperaba %d", code, http.StatusUnauthorized)
+	}
+}
+
+func TestFailureMiddlewareTrustedProxy(t *testing.T) {
+	router := newFailureRouter(t, []string{"10.0.0.0/8"})
+
+	// Detrás de un proxy de confianza, cada cliente tiene su propio límite
+	for i := 0; i < 2; i++ {
+		if code := failedAttempt(router, "10.0.0.1:5000", "198.51.100.1"); code != http.StatusUnauthorized {
+			t.Fatalf("intento %d = %d, se esperaba %d", i+1, code, http.StatusUnauthorized)
+		}
+	}
+	if code := failedAttempt(router, "10.0.0.1:5000", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Errorf("tercer intento = %d, se esperaba %d", code, http.StatusTooManyRequests)
+	}
+	if code := failedAttempt(router, "10.0.0.1:5000", "198.51.100.2"); code != http.StatusUnauthorized {
+		t.Errorf("otro cliente tras el proxy = %d, se esperaba %d", code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/tenant"
+	"client-data-compiler/pkg/response"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole exige que el principal autenticado tenga al menos el rol indicado
+func RequireRole(required models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := PrincipalFromContext(c)
+		if !ok {
+			unauthorized(c, "Se requieren credenciales")
+			return
+		}
+
+		if !principal.Role.Allows(required) {
+			response.ForbiddenWithCode(c, "INSUFFICIENT_ROLE",
+				fmt.Sprintf("Esta operación requiere el rol %s; el rol de %s es %s", required, principal.Subject, principal.Role))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Anonymous asigna a todas las peticiones un principal anónimo con el rol
+// indicado y el inquilino por defecto. Se usa cuando la autenticación está
+// deshabilitada para que RequireRole funcione igual.
+func Anonymous(role models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setPrincipal(c, &models.Principal{
+			Subject:    "anonymous",
+			AuthMethod: models.AuthMethodNone,
+			Role:       role,
+			Tenant:     tenant.Default,
+		})
+		c.Next()
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"client-data-compiler/internal/logging"
+	"client-data-compiler/internal/utils"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader cabecera con el ID de la petición
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern IDs de petición aceptados del cliente; el resto se
+// reemplazan para no registrar valores arbitrarios
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestID asigna a cada petición un ID, el de la cabecera X-Request-ID si es
+// válido o uno nuevo, lo devuelve en la respuesta y lo asocia al contexto de
+// la petición para que aparezca en todos sus registros
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = utils.GenerateID()
+		}
+
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// AccessLog registra cada petición al terminar, con nivel warn para las
+// respuestas 4xx y error para las 5xx. La query no se registra porque puede
+// contener datos personales.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+
+		slog.Log(c.Request.Context(), level, "Petición atendida",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"bytes", c.Writer.Size(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
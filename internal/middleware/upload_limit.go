@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"client-data-compiler/internal/config"
+	"client-data-compiler/pkg/response"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// uploadSlotKey clave del lugar de subida reservado en el contexto de gin
+	uploadSlotKey = "upload.slot"
+	// uploadRetryAfter espera sugerida cuando se alcanza el máximo de subidas
+	// simultáneas; no se sabe cuándo terminará la siguiente
+	uploadRetryAfter = 10 * time.Second
+)
+
+// UploadLimiter limita las subidas simultáneas de cada principal (o IP si la
+// petición es anónima)
+type UploadLimiter struct {
+	max    int
+	mu     sync.Mutex
+	active map[string]int
+}
+
+// NewUploadLimiter crea un limitador de max subidas simultáneas por principal
+func NewUploadLimiter(max int) *UploadLimiter {
+	if max <= 0 {
+		max = 1
+	}
+
+	return &UploadLimiter{
+		max:    max,
+		active: make(map[string]int),
+	}
+}
+
+// uploadSlot lugar de subida reservado por una petición
+type uploadSlot struct {
+	release func()
+	held    bool
+}
+
+// Middleware reserva un lugar de subida durante la petición. Si el principal
+// ya tiene max subidas en curso responde 429. El handler puede conservar el
+// lugar después de responder con HoldUploadSlot.
+func (l *UploadLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := clientKey(c)
+		if !l.acquire(key) {
+			response.TooManyRequests(c, "TOO_MANY_CONCURRENT_UPLOADS",
+				fmt.Sprintf("Ya tiene %d subidas en curso. Espere a que terminen para subir más archivos", l.max), uploadRetryAfter)
+			c.Abort()
+			return
+		}
+
+		slot := &uploadSlot{release: sync.OnceFunc(func() { l.release(key) })}
+		c.Set(uploadSlotKey, slot)
+
+		c.Next()
+
+		if !slot.held {
+			slot.release()
+		}
+	}
+}
+
+// HoldUploadSlot conserva el lugar de subida de la petición cuando esta
+// termina, p. ej. mientras el archivo se procesa en segundo plano. Devuelve
+// la función que lo libera, que no hace nada si la petición no reservó lugar.
+func HoldUploadSlot(c *gin.Context) func() {
+	value, exists := c.Get(uploadSlotKey)
+	if !exists {
+		return func() {}
+	}
+
+	slot := value.(*uploadSlot)
+	slot.held = true
+	return slot.release
+}
+
+func (l *UploadLimiter) acquire(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active[key] >= l.max {
+		return false
+	}
+	l.active[key]++
+	return true
+}
+
+func (l *UploadLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active[key]--
+	if l.active[key] <= 0 {
+		delete(l.active, key)
+	}
+}
+
+// LimitRequestBody rechaza con 413 las peticiones cuyo cuerpo excede maxBytes.
+// Si el tamaño no se declara, la lectura del cuerpo falla al superarlo; los
+// handlers lo detectan con IsRequestTooLarge.
+func LimitRequestBody(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			response.PayloadTooLarge(c, "REQUEST_TOO_LARGE",
+				fmt.Sprintf("La petición excede el tamaño máximo de %s", config.ByteSize(maxBytes)))
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// IsRequestTooLarge indica si err se debe a que el cuerpo de la petición
+// excedió el límite de LimitRequestBody
+func IsRequestTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
@@ -0,0 +1,218 @@
+package query
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Operadores de texto
+const (
+	opEq       = "eq"
+	opNe       = "ne"
+	opContains = "contains"
+	opStarts   = "starts"
+	opEnds     = "ends"
+	opRegex    = "regex"
+	opEmpty    = "empty"
+	opExists   = "exists"
+)
+
+// textOperators operadores de texto y sus alias
+var textOperators = map[string]string{
+	"eq":       opEq,
+	"exact":    opEq,
+	"ne":       opNe,
+	"contains": opContains,
+	"starts":   opStarts,
+	"prefix":   opStarts,
+	"ends":     opEnds,
+	"suffix":   opEnds,
+	"regex":    opRegex,
+	"empty":    opEmpty,
+	"exists":   opExists,
+}
+
+// comparators comparadores numéricos, de mayor a menor longitud para que
+// ">=" no se interprete como ">"
+var comparators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// textFields campos de texto del cliente
+var textFields = map[string]func(client *models.Client) string{
+	"clave":         func(c *models.Client) string { return c.Clave },
+	"nombre":        func(c *models.Client) string { return c.Nombre },
+	"correo":        func(c *models.Client) string { return c.Correo },
+	"telefono":      func(c *models.Client) string { return c.Telefono },
+	"source_upload": func(c *models.Client) string { return c.SourceUpload },
+}
+
+// numberFields campos numéricos del cliente. errors es el número de errores.
+var numberFields = map[string]func(client *models.Client) int{
+	"id":         func(c *models.Client) int { return c.ID },
+	"row_number": func(c *models.Client) int { return c.RowNumber },
+	"errors":     func(c *models.Client) int { return len(c.Errors) },
+}
+
+// parseCondition interpreta una condición individual
+func parseCondition(text string, pos int) (Expr, error) {
+	nameEnd := strings.IndexAny(text, ":=!<>")
+	if nameEnd <= 0 {
+		return nil, newError(pos, "condición inválida '%s': se esperaba campo:operador:valor", text)
+	}
+
+	field := strings.ToLower(text[:nameEnd])
+	rest := text[nameEnd:]
+
+	if rest[0] != ':' {
+		return parseComparison(field, rest, pos)
+	}
+
+	op, value, hasValue := opContains, rest[1:], true
+	if name, tail, found := strings.Cut(value, ":"); found && !strings.HasPrefix(value, `"`) {
+		canonical, ok := textOperators[strings.ToLower(name)]
+		switch {
+		case ok:
+			op, value = canonical, tail
+		case isIdentifier(name):
+			return nil, newError(pos, "operador desconocido '%s' en '%s'; si es parte del valor, escríbalo entre comillas", name, text)
+		default:
+			return nil, newError(pos, "valor con ':' sin comillas en '%s'", text)
+		}
+	} else if canonical, ok := textOperators[strings.ToLower(value)]; ok && (canonical == opEmpty || canonical == opExists) {
+		op, hasValue = canonical, false
+	}
+
+	if op == opEmpty || op == opExists {
+		if hasValue && value != "" {
+			return nil, newError(pos, "el operador %s no admite valor", op)
+		}
+	} else {
+		unquoted, err := unquote(value, pos)
+		if err != nil {
+			return nil, err
+		}
+		value = unquoted
+	}
+
+	return newFieldCondition(field, op, value, pos)
+}
+
+// parseComparison interpreta condiciones como row_number>100
+func parseComparison(field, rest string, pos int) (Expr, error) {
+	for _, cmp := range comparators {
+		if !strings.HasPrefix(rest, cmp) {
+			continue
+		}
+
+		value := rest[len(cmp):]
+
+		if get, ok := numberFields[field]; ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, newError(pos, "el campo %s requiere un valor numérico, se recibió '%s'", field, value)
+			}
+			return &numberCondition{field: field, op: cmp, value: n, get: get}, nil
+		}
+
+		// En campos de texto solo tienen sentido la igualdad y la desigualdad
+		switch cmp {
+		case "=":
+			return parseCondition(field+":"+opEq+":"+value, pos)
+		case "!=":
+			return parseCondition(field+":"+opNe+":"+value, pos)
+		}
+		return nil, newError(pos, "el campo %s no admite el comparador %s", field, cmp)
+	}
+
+	return nil, newError(pos, "comparador inválido en '%s%s'", field, rest)
+}
+
+// newFieldCondition construye la condición de texto sobre el campo indicado
+func newFieldCondition(field, op, value string, pos int) (Expr, error) {
+	cond := &textCondition{field: field, op: op, value: strings.ToLower(value)}
+
+	switch {
+	case field == "is_valid":
+		valid, err := strconv.ParseBool(value)
+		if err != nil || (op != opEq && op != opContains) {
+			return nil, newError(pos, "is_valid solo admite is_valid:true o is_valid:false")
+		}
+		return &boolCondition{field: field, value: valid, get: func(c *models.Client) bool { return c.IsValid }}, nil
+
+	case field == "errors":
+		// errors:exists / errors:empty indican si el cliente tiene algún error
+		if op != opExists && op != opEmpty {
+			return nil, newError(pos, "errors solo admite :exists, :empty o comparaciones numéricas")
+		}
+		cond.get = func(c *models.Client) (string, bool) { return "", len(c.Errors) > 0 }
+
+	case strings.HasPrefix(field, "errors."):
+		errorField := strings.TrimPrefix(field, "errors.")
+		if _, ok := textFields[errorField]; !ok {
+			return nil, newError(pos, "campo desconocido '%s'", field)
+		}
+		cond.get = func(c *models.Client) (string, bool) {
+			message, exists := c.Errors[errorField]
+			return message, exists
+		}
+
+	default:
+		get, ok := textFields[field]
+		if !ok {
+			if _, numeric := numberFields[field]; numeric {
+				return parseComparison(field, "="+value, pos)
+			}
+			return nil, newError(pos, "campo desconocido '%s'", field)
+		}
+		// En campos de texto, exists y empty consideran vacío un valor en blanco
+		cond.get = func(c *models.Client) (string, bool) {
+			text := get(c)
+			return text, strings.TrimSpace(text) != ""
+		}
+		if op != opExists && op != opEmpty && op != opRegex {
+			cond.get = func(c *models.Client) (string, bool) { return get(c), true }
+		}
+	}
+
+	if op == opRegex {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, newError(pos, "expresión regular inválida: %v", err)
+		}
+		cond.regex = re
+	}
+
+	return cond, nil
+}
+
+// isIdentifier indica si text es una palabra de letras, dígitos o guiones
+// bajos, como los nombres de los operadores
+func isIdentifier(text string) bool {
+	if text == "" {
+		return false
+	}
+	for _, r := range text {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// unquote elimina las comillas de un valor entrecomillado
+func unquote(value string, pos int) (string, error) {
+	if !strings.HasPrefix(value, `"`) {
+		if strings.Contains(value, `"`) {
+			return "", newError(pos, "comillas inesperadas en '%s'", value)
+		}
+		return value, nil
+	}
+
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		return "", newError(pos, "valor entrecomillado inválido %s", value)
+	}
+	return unquoted, nil
+}
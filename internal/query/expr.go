@@ -0,0 +1,135 @@
+package query
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type andExpr struct{ left, right Expr }
+
+func (e *andExpr) Match(client *models.Client) bool {
+	return e.left.Match(client) && e.right.Match(client)
+}
+
+func (e *andExpr) String() string {
+	return fmt.Sprintf("(%s AND %s)", e.left, e.right)
+}
+
+type orExpr struct{ left, right Expr }
+
+func (e *orExpr) Match(client *models.Client) bool {
+	return e.left.Match(client) || e.right.Match(client)
+}
+
+func (e *orExpr) String() string {
+	return fmt.Sprintf("(%s OR %s)", e.left, e.right)
+}
+
+type notExpr struct{ inner Expr }
+
+func (e *notExpr) Match(client *models.Client) bool {
+	return !e.inner.Match(client)
+}
+
+func (e *notExpr) String() string {
+	return "NOT " + e.inner.String()
+}
+
+// textCondition compara un campo de texto, o el mensaje de error de un campo
+type textCondition struct {
+	field string
+	op    string
+	value string
+	regex *regexp.Regexp
+	get   func(client *models.Client) (string, bool)
+}
+
+func (e *textCondition) Match(client *models.Client) bool {
+	text, present := e.get(client)
+
+	switch e.op {
+	case opExists:
+		return present
+	case opEmpty:
+		return !present
+	case opRegex:
+		return present && e.regex.MatchString(text)
+	}
+
+	if !present {
+		return false
+	}
+
+	text = strings.ToLower(text)
+	switch e.op {
+	case opEq:
+		return text == e.value
+	case opNe:
+		return text != e.value
+	case opStarts:
+		return strings.HasPrefix(text, e.value)
+	case opEnds:
+		return strings.HasSuffix(text, e.value)
+	default:
+		return strings.Contains(text, e.value)
+	}
+}
+
+func (e *textCondition) String() string {
+	switch e.op {
+	case opExists, opEmpty:
+		return e.field + ":" + e.op
+	case opRegex:
+		return e.field + ":" + e.op + ":" + strconv.Quote(e.regex.String())
+	}
+	return e.field + ":" + e.op + ":" + strconv.Quote(e.value)
+}
+
+// numberCondition compara un campo numérico
+type numberCondition struct {
+	field string
+	op    string
+	value int
+	get   func(client *models.Client) int
+}
+
+func (e *numberCondition) Match(client *models.Client) bool {
+	n := e.get(client)
+
+	switch e.op {
+	case "=":
+		return n == e.value
+	case "!=":
+		return n != e.value
+	case ">":
+		return n > e.value
+	case ">=":
+		return n >= e.value
+	case "<":
+		return n < e.value
+	default:
+		return n <= e.value
+	}
+}
+
+func (e *numberCondition) String() string {
+	return fmt.Sprintf("%s%s%d", e.field, e.op, e.value)
+}
+
+// boolCondition compara un campo booleano
+type boolCondition struct {
+	field string
+	value bool
+	get   func(client *models.Client) bool
+}
+
+func (e *boolCondition) Match(client *models.Client) bool {
+	return e.get(client) == e.value
+}
+
+func (e *boolCondition) String() string {
+	return fmt.Sprintf("%s:%t", e.field, e.value)
+}
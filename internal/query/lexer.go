@@ -0,0 +1,99 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenTerm
+)
+
+// token elemento léxico de una expresión. pos es la posición (en bytes) en
+// la que empieza dentro de la expresión original.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// tokenize divide la expresión en paréntesis, operadores lógicos y
+// condiciones. Las comillas dobles permiten incluir espacios y paréntesis en
+// los valores, p. ej. nombre:eq:"Juan Pérez".
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+
+	for i < len(input) {
+		c := input[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		default:
+			start := i
+			for i < len(input) && !isTermDelimiter(input[i]) {
+				if input[i] == '"' {
+					end, err := skipQuoted(input, i)
+					if err != nil {
+						return nil, err
+					}
+					i = end
+					continue
+				}
+				i++
+			}
+
+			text := input[start:i]
+			tokens = append(tokens, token{kind: keywordKind(text), text: text, pos: start})
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(input)})
+	return tokens, nil
+}
+
+// skipQuoted devuelve la posición siguiente a la cadena entre comillas que
+// empieza en start, respetando los escapes con barra invertida
+func skipQuoted(input string, start int) (int, error) {
+	for i := start + 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+	return 0, newError(start, "comillas sin cerrar")
+}
+
+func isTermDelimiter(c byte) bool {
+	return unicode.IsSpace(rune(c)) || c == '(' || c == ')'
+}
+
+// keywordKind identifica los operadores lógicos, sin distinguir mayúsculas
+func keywordKind(text string) tokenKind {
+	switch strings.ToUpper(text) {
+	case "AND", "&&":
+		return tokenAnd
+	case "OR", "||":
+		return tokenOr
+	case "NOT", "!":
+		return tokenNot
+	}
+	return tokenTerm
+}
@@ -0,0 +1,69 @@
+// Package query implementa un lenguaje de filtros para clientes.
+//
+// Una expresión combina condiciones con AND, OR, NOT y paréntesis; dos
+// condiciones seguidas sin operador se combinan con AND. Cada condición tiene
+// la forma campo:operador:valor, campo:valor (contiene) o campo<comparador>número:
+//
+//	correo:ends:@hotmail.com AND errors.telefono:exists AND row_number>100
+//	(nombre:starts:ana OR nombre:regex:"^jua?n") NOT clave:empty
+//
+// Operadores de texto: eq, contains, starts, ends, regex, empty y exists. Las
+// comparaciones de texto no distinguen mayúsculas (salvo regex; use (?i)).
+// En campo:valor, un valor con ':' debe ir entre comillas, p. ej.
+// correo:"a:b", para no confundirse con un operador.
+// Los campos numéricos (id, row_number, errors) admiten =, !=, >, >=, < y <=.
+package query
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"fmt"
+)
+
+// Expr expresión compilada; implementa models.ClientMatcher
+type Expr interface {
+	Match(client *models.Client) bool
+	String() string
+}
+
+// Error error de sintaxis en una expresión
+type Error struct {
+	Pos     int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("posición %d: %s", e.Pos, e.Message)
+}
+
+func newError(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+// Parse compila una expresión de filtro
+func Parse(input string) (Expr, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, newError(0, "la expresión está vacía")
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, newError(tok.pos, "se esperaba fin de la expresión, se encontró '%s'", tok.text)
+	}
+
+	return expr, nil
+}
+
+// And combina dos expresiones; se cumple si se cumplen ambas
+func And(left, right Expr) Expr {
+	return &andExpr{left: left, right: right}
+}
@@ -0,0 +1,239 @@
+package query
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParsePrecedence(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "AND implícito",
+			input: "nombre:ana correo:ends:@gmail.com",
+			want:  `(nombre:contains:"ana" AND correo:ends:"@gmail.com")`,
+		},
+		{
+			name:  "AND antes que OR",
+			input: "clave:eq:a OR clave:eq:b AND clave:eq:c",
+			want:  `(clave:eq:"a" OR (clave:eq:"b" AND clave:eq:"c"))`,
+		},
+		{
+			name:  "AND implícito antes que OR",
+			input: "clave:eq:a clave:eq:b OR clave:eq:c",
+			want:  `((clave:eq:"a" AND clave:eq:"b") OR clave:eq:"c")`,
+		},
+		{
+			name:  "paréntesis",
+			input: "(clave:eq:a OR clave:eq:b) clave:eq:c",
+			want:  `((clave:eq:"a" OR clave:eq:"b") AND clave:eq:"c")`,
+		},
+		{
+			name:  "NOT solo afecta a la condición siguiente",
+			input: "NOT clave:empty OR nombre:exists",
+			want:  `(NOT clave:empty OR nombre:exists)`,
+		},
+		{
+			name:  "NOT sobre un grupo",
+			input: "not (clave:empty || nombre:exists)",
+			want:  `NOT (clave:empty OR nombre:exists)`,
+		},
+		{
+			name:  "operadores simbólicos",
+			input: "! clave:empty && row_number>=10",
+			want:  `(NOT clave:empty AND row_number>=10)`,
+		},
+		{
+			name:  "valor entrecomillado con espacios y paréntesis",
+			input: `nombre:eq:"Juan (hijo) Pérez"`,
+			want:  `nombre:eq:"juan (hijo) pérez"`,
+		},
+		{
+			name:  "comparación de texto",
+			input: "clave=ABC clave!=XYZ",
+			want:  `(clave:eq:"abc" AND clave:ne:"xyz")`,
+		},
+		{
+			name:  "campo numérico con operador de texto",
+			input: "id:5",
+			want:  "id=5",
+		},
+		{
+			name:  "alias de operadores",
+			input: "clave:prefix:A clave:suffix:Z clave:exact:AZ",
+			want:  `((clave:starts:"a" AND clave:ends:"z") AND clave:eq:"az")`,
+		},
+		{
+			name:  "dos puntos entre comillas",
+			input: `correo:"end:@hotmail.com" nombre:eq:"a:b"`,
+			want:  `(correo:contains:"end:@hotmail.com" AND nombre:eq:"a:b")`,
+		},
+		{
+			name:  "dos puntos tras el operador",
+			input: "source_upload:eq:a:b",
+			want:  `source_upload:eq:"a:b"`,
+		},
+		{
+			name:  "regex conserva mayúsculas",
+			input: `nombre:regex:"^Ju?an$"`,
+			want:  `nombre:regex:"^Ju?an$"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.input, err)
+			}
+			if got := expr.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %s, se esperaba %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		pos     int
+		message string
+	}{
+		{name: "vacía", input: "   ", pos: 0, message: "vacía"},
+		{name: "paréntesis sin cerrar", input: "(clave:a OR nombre:b", pos: 20, message: "posición 0"},
+		{name: "paréntesis sin abrir", input: "clave:a)", pos: 7, message: "fin de la expresión"},
+		{name: "paréntesis vacíos", input: "clave:a ()", pos: 9, message: "se esperaba una condición"},
+		{name: "comillas sin cerrar", input: `clave:a nombre:eq:"Juan`, pos: 18, message: "comillas sin cerrar"},
+		{name: "comillas inesperadas", input: `nombre:Ju"an"`, pos: 0, message: "comillas inesperadas"},
+		{name: "regex inválida", input: `clave:a nombre:regex:"[a-"`, pos: 8, message: "expresión regular inválida"},
+		{name: "campo desconocido", input: "clave:a direccion:centro", pos: 8, message: "campo desconocido 'direccion'"},
+		{name: "campo de error desconocido", input: "errors.direccion:exists", pos: 0, message: "campo desconocido 'errors.direccion'"},
+		{name: "valor numérico inválido", input: "row_number>diez", pos: 0, message: "valor numérico"},
+		{name: "comparador en campo de texto", input: "nombre>5", pos: 0, message: "no admite el comparador"},
+		{name: "sin operador", input: "nombre", pos: 0, message: "campo:operador:valor"},
+		{name: "operador incompleto", input: "clave:a AND", pos: 11, message: "termina inesperadamente"},
+		{name: "exists con valor", input: "clave:exists:x", pos: 0, message: "no admite valor"},
+		{name: "is_valid inválido", input: "is_valid:quizas", pos: 0, message: "is_valid"},
+		{name: "operador desconocido", input: "clave:a correo:end:@hotmail.com", pos: 8, message: "operador desconocido 'end'"},
+		{name: "operador desconocido con valor vacío", input: "nombre:igual:", pos: 0, message: "operador desconocido 'igual'"},
+		{name: "dos puntos sin comillas", input: "correo:ana@x.com:80", pos: 0, message: "sin comillas"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			if err == nil {
+				t.Fatalf("Parse(%q) no devolvió error", tt.input)
+			}
+
+			var syntaxErr *Error
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse(%q) error %T, se esperaba *Error", tt.input, err)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("Parse(%q) posición %d, se esperaba %d (%v)", tt.input, syntaxErr.Pos, tt.pos, err)
+			}
+			if !strings.Contains(syntaxErr.Message, tt.message) {
+				t.Errorf("Parse(%q) mensaje %q, se esperaba que contuviera %q", tt.input, syntaxErr.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	ana := &models.Client{
+		ID:        7,
+		Clave:     "C001",
+		Nombre:    "Ana López",
+		Correo:    "ana@hotmail.com",
+		Telefono:  "9621234567",
+		IsValid:   false,
+		RowNumber: 150,
+		Errors:    map[string]string{"telefono": "Lada no permitida"},
+	}
+	juan := &models.Client{
+		ID:        8,
+		Clave:     "C002",
+		Nombre:    "Juan Pérez",
+		Correo:    "juan@gmail.com",
+		Telefono:  "  ",
+		IsValid:   true,
+		RowNumber: 20,
+	}
+
+	tests := []struct {
+		input string
+		ana   bool
+		juan  bool
+	}{
+		{"correo:ends:@hotmail.com AND errors.telefono:exists AND row_number>100", true, false},
+		{"nombre:ANA", true, false},
+		{"nombre:eq:\"juan pérez\"", false, true},
+		{"nombre:starts:ju", false, true},
+		{"clave:ne:C001", false, true},
+		{"clave=c001", true, false},
+		{"clave!=c001", false, true},
+		{"nombre:regex:^Ana", true, false},
+		{"nombre:regex:^ana", false, false},
+		{`nombre:regex:"(?i)^ana"`, true, false},
+		{"telefono:empty", false, true},
+		{"telefono:exists", true, false},
+		{"errors:exists", true, false},
+		{"errors:empty", false, true},
+		{"errors.telefono:contains:lada", true, false},
+		{"errors.correo:exists", false, false},
+		{"errors>=1", true, false},
+		{"errors=0", false, true},
+		{"is_valid:true", false, true},
+		{"is_valid:false", true, false},
+		{"id>7", false, true},
+		{"id<=7", true, false},
+		{"row_number<20", false, false},
+		{"row_number<=20", false, true},
+		{"row_number!=20", true, false},
+		{"NOT is_valid:true", true, false},
+		{"is_valid:true OR row_number>100", true, true},
+		{"(nombre:ana OR nombre:juan) correo:ends:gmail.com", false, true},
+		{"NOT (nombre:ana OR nombre:juan)", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			expr, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.input, err)
+			}
+			if got := expr.Match(ana); got != tt.ana {
+				t.Errorf("Match(ana) = %t, se esperaba %t", got, tt.ana)
+			}
+			if got := expr.Match(juan); got != tt.juan {
+				t.Errorf("Match(juan) = %t, se esperaba %t", got, tt.juan)
+			}
+		})
+	}
+}
+
+func TestAnd(t *testing.T) {
+	left, err := Parse("is_valid:true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	right, err := Parse("row_number>10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expr := And(left, right)
+	if !expr.Match(&models.Client{IsValid: true, RowNumber: 11}) {
+		t.Error("se esperaba que coincidiera con ambas condiciones")
+	}
+	if expr.Match(&models.Client{IsValid: true, RowNumber: 10}) {
+		t.Error("no debería coincidir si falla la condición derecha")
+	}
+}
@@ -3,72 +3,100 @@ package repository
 import (
 	"client-data-compiler/internal/domain/errors"
 	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/tenant"
+	"context"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
-// ClientRepository interfaz para el repositorio de clientes
+// ClientRepository interfaz para el repositorio de clientes. Todas las
+// operaciones se limitan a los clientes del inquilino de ctx.
 type ClientRepository interface {
-	Create(client *models.Client) (*models.Client, error)
-	GetByID(id int) (*models.Client, error)
-	GetByClave(clave string) (*models.Client, error)
-	GetAll() ([]*models.Client, error)
-	Update(id int, client *models.Client) (*models.Client, error)
-	Delete(id int) error
-	Clear() error
-	Count() int
-	FindByFilter(filter *models.ClientFilter) ([]*models.Client, error)
-	BatchCreate(clients []*models.Client) ([]*models.Client, error)
-	BatchUpdate(clients []*models.Client) ([]*models.Client, error)
-	GetDuplicateKeys() map[string][]int
+	Create(ctx context.Context, client *models.Client) (*models.Client, error)
+	GetByID(ctx context.Context, id int) (*models.Client, error)
+	GetByClave(ctx context.Context, clave string) (*models.Client, error)
+	GetAll(ctx context.Context) ([]*models.Client, error)
+	Update(ctx context.Context, id int, client *models.Client) (*models.Client, error)
+	Delete(ctx context.Context, id int) error
+	Clear(ctx context.Context) error
+	Count(ctx context.Context) (int, error)
+	FindByFilter(ctx context.Context, filter *models.ClientFilter) ([]*models.Client, error)
+	BatchCreate(ctx context.Context, clients []*models.Client) ([]*models.Client, error)
+	BatchUpdate(ctx context.Context, clients []*models.Client) ([]*models.Client, error)
+	GetDuplicateKeys(ctx context.Context) (map[string][]int, error)
 }
 
-// inMemoryClientRepository implementación en memoria del repositorio
+// inMemoryClientRepository implementación en memoria del repositorio. Los
+// clientes se guardan separados por inquilino y cada operación solo ve los
+// del inquilino de su contexto.
 type inMemoryClientRepository struct {
-	clients map[int]*models.Client
+	tenants map[string]*clientPartition
 	mutex   sync.RWMutex
+}
+
+// clientPartition clientes de un inquilino
+type clientPartition struct {
+	clients map[int]*models.Client
+	// byClave IDs de los clientes de cada clave, para no recorrer clients
+	byClave map[string]map[int]struct{}
 	lastID  int
 }
 
 // NewInMemoryClientRepository crea una nueva instancia del repositorio en memoria
 func NewInMemoryClientRepository() ClientRepository {
 	return &inMemoryClientRepository{
+		tenants: make(map[string]*clientPartition),
+	}
+}
+
+func newClientPartition() *clientPartition {
+	return &clientPartition{
 		clients: make(map[int]*models.Client),
+		byClave: make(map[string]map[int]struct{}),
 		lastID:  0,
 	}
 }
 
 // Create crea un nuevo cliente
-func (r *inMemoryClientRepository) Create(client *models.Client) (*models.Client, error) {
+func (r *inMemoryClientRepository) Create(ctx context.Context, client *models.Client) (*models.Client, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	p := r.writablePartition(ctx)
+
 	// Verificar clave duplicada
-	for _, existingClient := range r.clients {
-		if existingClient.Clave == client.Clave {
-			return nil, errors.ErrDuplicateClientKey
-		}
+	if len(p.byClave[client.Clave]) > 0 {
+		return nil, errors.ErrDuplicateClientKey
 	}
 
 	// Asignar nuevo ID
-	r.lastID++
-	client.ID = r.lastID
+	p.lastID++
+	client.ID = p.lastID
 	client.CreatedAt = time.Now()
 	client.UpdatedAt = time.Now()
 
 	// Guardar cliente
-	r.clients[client.ID] = client
+	p.store(client)
 
 	return client, nil
 }
 
 // GetByID obtiene un cliente por su ID
-func (r *inMemoryClientRepository) GetByID(id int) (*models.Client, error) {
+func (r *inMemoryClientRepository) GetByID(ctx context.Context, id int) (*models.Client, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	client, exists := r.clients[id]
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	p := r.partition(ctx)
+
+	client, exists := p.clients[id]
 	if !exists {
 		return nil, errors.ErrClientNotFound
 	}
@@ -77,26 +105,34 @@ func (r *inMemoryClientRepository) GetByID(id int) (*models.Client, error) {
 }
 
 // GetByClave obtiene un cliente por su clave
-func (r *inMemoryClientRepository) GetByClave(clave string) (*models.Client, error) {
+func (r *inMemoryClientRepository) GetByClave(ctx context.Context, clave string) (*models.Client, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	for _, client := range r.clients {
-		if client.Clave == clave {
-			return client, nil
-		}
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	p := r.partition(ctx)
+
+	for id := range p.byClave[clave] {
+		return p.clients[id], nil
 	}
 
 	return nil, errors.ErrClientNotFound
 }
 
 // GetAll obtiene todos los clientes
-func (r *inMemoryClientRepository) GetAll() ([]*models.Client, error) {
+func (r *inMemoryClientRepository) GetAll(ctx context.Context) ([]*models.Client, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	clients := make([]*models.Client, 0, len(r.clients))
-	for _, client := range r.clients {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	p := r.partition(ctx)
+
+	clients := make([]*models.Client, 0, len(p.clients))
+	for _, client := range p.clients {
 		clients = append(clients, client)
 	}
 
@@ -104,19 +140,24 @@ func (r *inMemoryClientRepository) GetAll() ([]*models.Client, error) {
 }
 
 // Update actualiza un cliente existente
-func (r *inMemoryClientRepository) Update(id int, updatedClient *models.Client) (*models.Client, error) {
+func (r *inMemoryClientRepository) Update(ctx context.Context, id int, updatedClient *models.Client) (*models.Client, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	p := r.partition(ctx)
+
 	// Verificar que el cliente existe
-	existingClient, exists := r.clients[id]
+	existingClient, exists := p.clients[id]
 	if !exists {
 		return nil, errors.ErrClientNotFound
 	}
 
 	// Verificar clave duplicada (excluyendo el cliente actual)
-	for clientID, client := range r.clients {
-		if clientID != id && client.Clave == updatedClient.Clave {
+	for clientID := range p.byClave[updatedClient.Clave] {
+		if clientID != id {
 			return nil, errors.ErrDuplicateClientKey
 		}
 	}
@@ -127,56 +168,84 @@ func (r *inMemoryClientRepository) Update(id int, updatedClient *models.Client)
 	updatedClient.UpdatedAt = time.Now()
 
 	// Actualizar cliente
-	r.clients[id] = updatedClient
+	p.unindex(existingClient)
+	p.store(updatedClient)
 
 	return updatedClient, nil
 }
 
 // Delete elimina un cliente
-func (r *inMemoryClientRepository) Delete(id int) error {
+func (r *inMemoryClientRepository) Delete(ctx context.Context, id int) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if _, exists := r.clients[id]; !exists {
+	if err := ctx.Err(); err != nil {
+		return errors.NewContextError(err)
+	}
+	p := r.partition(ctx)
+
+	client, exists := p.clients[id]
+	if !exists {
 		return errors.ErrClientNotFound
 	}
 
-	delete(r.clients, id)
+	p.unindex(client)
+	delete(p.clients, id)
 	return nil
 }
 
 // Clear elimina todos los clientes
-func (r *inMemoryClientRepository) Clear() error {
+func (r *inMemoryClientRepository) Clear(ctx context.Context) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	r.clients = make(map[int]*models.Client)
-	r.lastID = 0
+	if err := ctx.Err(); err != nil {
+		return errors.NewContextError(err)
+	}
+
+	delete(r.tenants, tenant.FromContext(ctx))
 
 	return nil
 }
 
 // Count obtiene el número total de clientes
-func (r *inMemoryClientRepository) Count() int {
+func (r *inMemoryClientRepository) Count(ctx context.Context) (int, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	return len(r.clients)
+	if err := ctx.Err(); err != nil {
+		return 0, errors.NewContextError(err)
+	}
+	p := r.partition(ctx)
+
+	return len(p.clients), nil
 }
 
 // FindByFilter busca clientes por filtros
-func (r *inMemoryClientRepository) FindByFilter(filter *models.ClientFilter) ([]*models.Client, error) {
+func (r *inMemoryClientRepository) FindByFilter(ctx context.Context, filter *models.ClientFilter) ([]*models.Client, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	p := r.partition(ctx)
+
 	var results []*models.Client
 
-	for _, client := range r.clients {
+	for _, client := range p.clients {
 		if r.matchesFilter(client, filter) {
 			results = append(results, client)
 		}
 	}
 
+	// Ordenar por ID (el orden del mapa es aleatorio) y después por los
+	// campos pedidos, antes de paginar
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID < results[j].ID
+	})
+	models.SortClients(results, filter.Sort)
+
 	// Aplicar paginación si está especificada
 	if filter.Page > 0 && filter.Limit > 0 {
 		start := (filter.Page - 1) * filter.Limit
@@ -197,21 +266,26 @@ func (r *inMemoryClientRepository) FindByFilter(filter *models.ClientFilter) ([]
 }
 
 // BatchCreate crea múltiples clientes
-func (r *inMemoryClientRepository) BatchCreate(clients []*models.Client) ([]*models.Client, error) {
+func (r *inMemoryClientRepository) BatchCreate(ctx context.Context, clients []*models.Client) ([]*models.Client, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	p := r.writablePartition(ctx)
+
 	createdClients := make([]*models.Client, 0, len(clients))
 
 	for _, client := range clients {
 		// Asignar nuevo ID
-		r.lastID++
-		client.ID = r.lastID
+		p.lastID++
+		client.ID = p.lastID
 		client.CreatedAt = time.Now()
 		client.UpdatedAt = time.Now()
 
 		// Guardar cliente
-		r.clients[client.ID] = client
+		p.store(client)
 		createdClients = append(createdClients, client)
 	}
 
@@ -219,16 +293,22 @@ func (r *inMemoryClientRepository) BatchCreate(clients []*models.Client) ([]*mod
 }
 
 // BatchUpdate actualiza múltiples clientes
-func (r *inMemoryClientRepository) BatchUpdate(clients []*models.Client) ([]*models.Client, error) {
+func (r *inMemoryClientRepository) BatchUpdate(ctx context.Context, clients []*models.Client) ([]*models.Client, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	p := r.partition(ctx)
+
 	updatedClients := make([]*models.Client, 0, len(clients))
 
 	for _, client := range clients {
-		if _, exists := r.clients[client.ID]; exists {
+		if existingClient, exists := p.clients[client.ID]; exists {
 			client.UpdatedAt = time.Now()
-			r.clients[client.ID] = client
+			p.unindex(existingClient)
+			p.store(client)
 			updatedClients = append(updatedClients, client)
 		}
 	}
@@ -237,32 +317,73 @@ func (r *inMemoryClientRepository) BatchUpdate(clients []*models.Client) ([]*mod
 }
 
 // GetDuplicateKeys obtiene las claves duplicadas
-func (r *inMemoryClientRepository) GetDuplicateKeys() map[string][]int {
+func (r *inMemoryClientRepository) GetDuplicateKeys(ctx context.Context) (map[string][]int, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	keyCount := make(map[string][]int)
-
-	// Contar ocurrencias de cada clave
-	for _, client := range r.clients {
-		if client.Clave != "" {
-			keyCount[client.Clave] = append(keyCount[client.Clave], client.ID)
-		}
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
 	}
+	p := r.partition(ctx)
 
-	// Filtrar solo las duplicadas
+	// Filtrar solo las claves con más de un cliente
 	duplicates := make(map[string][]int)
-	for key, ids := range keyCount {
-		if len(ids) > 1 {
-			duplicates[key] = ids
+	for key, ids := range p.byClave {
+		if key == "" || len(ids) < 2 {
+			continue
+		}
+		for id := range ids {
+			duplicates[key] = append(duplicates[key], id)
 		}
 	}
 
-	return duplicates
+	return duplicates, nil
 }
 
 // Métodos auxiliares privados
 
+// partition obtiene los clientes del inquilino de ctx. Si el inquilino aún
+// no tiene clientes devuelve una partición vacía que no se guarda.
+func (r *inMemoryClientRepository) partition(ctx context.Context) *clientPartition {
+	if p, exists := r.tenants[tenant.FromContext(ctx)]; exists {
+		return p
+	}
+	return newClientPartition()
+}
+
+// writablePartition obtiene los clientes del inquilino de ctx, creándolos si
+// no existen (requiere el lock de escritura)
+func (r *inMemoryClientRepository) writablePartition(ctx context.Context) *clientPartition {
+	id := tenant.FromContext(ctx)
+	p, exists := r.tenants[id]
+	if !exists {
+		p = newClientPartition()
+		r.tenants[id] = p
+	}
+	return p
+}
+
+// store guarda un cliente y lo indexa por clave (requiere el lock de escritura)
+func (p *clientPartition) store(client *models.Client) {
+	p.clients[client.ID] = client
+
+	ids, exists := p.byClave[client.Clave]
+	if !exists {
+		ids = make(map[int]struct{}, 1)
+		p.byClave[client.Clave] = ids
+	}
+	ids[client.ID] = struct{}{}
+}
+
+// unindex elimina un cliente del índice por clave (requiere el lock de escritura)
+func (p *clientPartition) unindex(client *models.Client) {
+	ids := p.byClave[client.Clave]
+	delete(ids, client.ID)
+	if len(ids) == 0 {
+		delete(p.byClave, client.Clave)
+	}
+}
+
 // matchesFilter verifica si un cliente coincide con los filtros
 func (r *inMemoryClientRepository) matchesFilter(client *models.Client, filter *models.ClientFilter) bool {
 	// Filtro por clave
@@ -312,18 +433,23 @@ func (r *inMemoryClientRepository) containsIgnoreCase(haystack, needle string) b
 }
 
 // GetStats obtiene estadísticas del repositorio
-func (r *inMemoryClientRepository) GetStats() (*models.ClientStats, error) {
+func (r *inMemoryClientRepository) GetStats(ctx context.Context) (*models.ClientStats, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	p := r.partition(ctx)
+
 	stats := &models.ClientStats{
-		Total:         len(r.clients),
+		Total:         len(p.clients),
 		Valid:         0,
 		Invalid:       0,
 		ErrorsByField: make(map[string]int),
 	}
 
-	for _, client := range r.clients {
+	for _, client := range p.clients {
 		if client.IsValid {
 			stats.Valid++
 		} else {
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"context"
+	"fmt"
+	"testing"
+)
+
+// benchmarkSizes clientes con los que se llena el repositorio antes de medir
+var benchmarkSizes = []int{1_000, 100_000, 1_000_000}
+
+// benchmarkRepository crea un repositorio con size clientes de claves C0000000...
+func benchmarkRepository(b *testing.B, size int) ClientRepository {
+	if size >= 1_000_000 && testing.Short() {
+		b.Skip("repositorio de un millón de clientes omitido con -short")
+	}
+
+	clients := make([]*models.Client, size)
+	for i := range clients {
+		clients[i] = &models.Client{
+			Clave:    fmt.Sprintf("C%07d", i),
+			Nombre:   "Cliente",
+			Correo:   fmt.Sprintf("cliente%d@gmail.com", i),
+			Telefono: fmt.Sprintf("962%07d", i),
+		}
+	}
+
+	repo := NewInMemoryClientRepository()
+	if _, err := repo.BatchCreate(context.Background(), clients); err != nil {
+		b.Fatal(err)
+	}
+	return repo
+}
+
+func BenchmarkClientRepositoryCreate(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("clientes=%d", size), func(b *testing.B) {
+			repo := benchmarkRepository(b, size)
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				client := &models.Client{Clave: fmt.Sprintf("N%09d", i), Nombre: "Nuevo"}
+				if _, err := repo.Create(ctx, client); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkClientRepositoryGetByClave(b *testing.B) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("clientes=%d", size), func(b *testing.B) {
+			repo := benchmarkRepository(b, size)
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.GetByClave(ctx, fmt.Sprintf("C%07d", i%size)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package repository
+
+import "os"
+
+// syncFile fuerza la escritura de un archivo en el dispositivo, para que
+// sobreviva a un corte de energía tras el apagado
+func syncFile(filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"client-data-compiler/internal/domain/errors"
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/tenant"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// SavedFilterRepository interfaz para los filtros guardados. Todas las
+// operaciones se limitan a los filtros del inquilino de ctx y fallan sin
+// modificar el registro si ctx ya terminó.
+type SavedFilterRepository interface {
+	Create(ctx context.Context, filter *models.SavedFilter) (*models.SavedFilter, error)
+	GetByName(ctx context.Context, name string) (*models.SavedFilter, error)
+	GetAll(ctx context.Context) ([]*models.SavedFilter, error)
+	Update(ctx context.Context, filter *models.SavedFilter) (*models.SavedFilter, error)
+	Delete(ctx context.Context, name string) error
+	// Close guarda el registro en disco y rechaza las escrituras posteriores
+	// con SHUTTING_DOWN
+	Close() error
+}
+
+// fileSavedFilterRepository filtros guardados en memoria persistidos en un
+// archivo JSON
+type fileSavedFilterRepository struct {
+	filters  map[savedFilterKey]*models.SavedFilter
+	mutex    sync.RWMutex
+	filePath string
+	// closed indica que el registro se cerró y no admite más escrituras
+	closed bool
+}
+
+// NewFileSavedFilterRepository crea el registro de filtros guardados cargando
+// el archivo JSON indicado si ya existe
+func NewFileSavedFilterRepository(filePath string) (SavedFilterRepository, error) {
+	r := &fileSavedFilterRepository{
+		filters:  make(map[savedFilterKey]*models.SavedFilter),
+		filePath: filePath,
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// savedFilterKey identifica un filtro dentro de su inquilino
+type savedFilterKey struct {
+	tenant string
+	name   string
+}
+
+// Create registra un nuevo filtro en el inquilino de ctx
+func (r *fileSavedFilterRepository) Create(ctx context.Context, filter *models.SavedFilter) (*models.SavedFilter, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	if r.closed {
+		return nil, errors.ErrShuttingDown
+	}
+
+	key := r.key(ctx, filter.Name)
+	if _, exists := r.filters[key]; exists {
+		return nil, errors.ErrSavedFilterAlreadyExists
+	}
+
+	stored := *filter
+	stored.Tenant = key.tenant
+	r.filters[key] = &stored
+
+	if err := r.persist(); err != nil {
+		delete(r.filters, key)
+		return nil, err
+	}
+
+	return r.copyOf(&stored), nil
+}
+
+// GetByName obtiene un filtro por su nombre
+func (r *fileSavedFilterRepository) GetByName(ctx context.Context, name string) (*models.SavedFilter, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+
+	filter, exists := r.filters[r.key(ctx, name)]
+	if !exists {
+		return nil, errors.ErrSavedFilterNotFound
+	}
+
+	return r.copyOf(filter), nil
+}
+
+// GetAll obtiene todos los filtros ordenados por nombre
+func (r *fileSavedFilterRepository) GetAll(ctx context.Context) ([]*models.SavedFilter, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+
+	tenantID := tenant.FromContext(ctx)
+	filters := make([]*models.SavedFilter, 0)
+	for _, filter := range r.sorted() {
+		if filter.Tenant == tenantID {
+			filters = append(filters, r.copyOf(filter))
+		}
+	}
+
+	return filters, nil
+}
+
+// Update reemplaza un filtro existente
+func (r *fileSavedFilterRepository) Update(ctx context.Context, filter *models.SavedFilter) (*models.SavedFilter, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	if r.closed {
+		return nil, errors.ErrShuttingDown
+	}
+
+	key := r.key(ctx, filter.Name)
+	previous, exists := r.filters[key]
+	if !exists {
+		return nil, errors.ErrSavedFilterNotFound
+	}
+
+	stored := *filter
+	stored.Tenant = key.tenant
+	r.filters[key] = &stored
+
+	if err := r.persist(); err != nil {
+		r.filters[key] = previous
+		return nil, err
+	}
+
+	return r.copyOf(&stored), nil
+}
+
+// Delete elimina un filtro
+func (r *fileSavedFilterRepository) Delete(ctx context.Context, name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return errors.NewContextError(err)
+	}
+	if r.closed {
+		return errors.ErrShuttingDown
+	}
+
+	key := r.key(ctx, name)
+	previous, exists := r.filters[key]
+	if !exists {
+		return errors.ErrSavedFilterNotFound
+	}
+
+	delete(r.filters, key)
+
+	if err := r.persist(); err != nil {
+		r.filters[key] = previous
+		return err
+	}
+
+	return nil
+}
+
+// Close guarda el registro en disco, asegurando que llegue al dispositivo
+func (r *fileSavedFilterRepository) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	if err := r.persist(); err != nil {
+		return err
+	}
+	if err := syncFile(r.filePath); err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("no se pudo guardar el registro de filtros: %v", err))
+	}
+
+	return nil
+}
+
+// Métodos auxiliares privados
+
+// copyOf devuelve una copia para que los llamadores no modifiquen el registro
+func (r *fileSavedFilterRepository) copyOf(filter *models.SavedFilter) *models.SavedFilter {
+	c := *filter
+	return &c
+}
+
+// key obtiene la clave del filtro name en el inquilino de ctx
+func (r *fileSavedFilterRepository) key(ctx context.Context, name string) savedFilterKey {
+	return savedFilterKey{tenant: tenant.FromContext(ctx), name: name}
+}
+
+// sorted obtiene los filtros de todos los inquilinos ordenados por inquilino
+// y nombre
+func (r *fileSavedFilterRepository) sorted() []*models.SavedFilter {
+	filters := make([]*models.SavedFilter, 0, len(r.filters))
+	for _, filter := range r.filters {
+		filters = append(filters, filter)
+	}
+
+	sort.Slice(filters, func(i, j int) bool {
+		if filters[i].Tenant != filters[j].Tenant {
+			return filters[i].Tenant < filters[j].Tenant
+		}
+		return filters[i].Name < filters[j].Name
+	})
+
+	return filters
+}
+
+// load carga el registro desde disco si el archivo existe
+func (r *fileSavedFilterRepository) load() error {
+	data, err := os.ReadFile(r.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("no se pudo leer el registro de filtros: %v", err))
+	}
+
+	var filters []*models.SavedFilter
+	if err := json.Unmarshal(data, &filters); err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("registro de filtros corrupto: %v", err))
+	}
+
+	for _, filter := range filters {
+		// Los filtros anteriores a los inquilinos pertenecen al inquilino por defecto
+		filter.Tenant = tenant.Normalize(filter.Tenant)
+		r.filters[savedFilterKey{tenant: filter.Tenant, name: filter.Name}] = filter
+	}
+
+	return nil
+}
+
+// persist escribe el registro completo a disco (requiere el lock de escritura).
+// Se escribe a un archivo temporal y se renombra para no dejar archivos a medias.
+func (r *fileSavedFilterRepository) persist() error {
+	data, err := json.MarshalIndent(r.sorted(), "", "  ")
+	if err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("no se pudo serializar el registro de filtros: %v", err))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.filePath), 0755); err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("no se pudo crear el directorio del registro: %v", err))
+	}
+
+	tmpPath := r.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("no se pudo escribir el registro de filtros: %v", err))
+	}
+
+	if err := os.Rename(tmpPath, r.filePath); err != nil {
+		os.Remove(tmpPath)
+		return errors.NewDatabaseError(fmt.Sprintf("no se pudo guardar el registro de filtros: %v", err))
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"client-data-compiler/internal/domain/errors"
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/tenant"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// UploadRepository interfaz para el registro de archivos subidos. Todas las
+// operaciones se limitan a los uploads del inquilino de ctx y fallan sin
+// modificar el registro si ctx ya terminó.
+type UploadRepository interface {
+	Create(ctx context.Context, upload *models.Upload) (*models.Upload, error)
+	GetByID(ctx context.Context, id string) (*models.Upload, error)
+	GetByStoredName(ctx context.Context, storedName string) (*models.Upload, error)
+	GetAll(ctx context.Context) ([]*models.Upload, error)
+	Update(ctx context.Context, upload *models.Upload) (*models.Upload, error)
+	Delete(ctx context.Context, id string) error
+	// Close guarda el registro en disco y rechaza las escrituras posteriores
+	// con SHUTTING_DOWN
+	Close() error
+}
+
+// fileUploadRepository registro en memoria persistido en un archivo JSON
+type fileUploadRepository struct {
+	uploads  map[string]*models.Upload
+	mutex    sync.RWMutex
+	filePath string
+	// closed indica que el registro se cerró y no admite más escrituras
+	closed bool
+}
+
+// NewFileUploadRepository crea el registro de uploads cargando el archivo JSON
+// indicado si ya existe
+func NewFileUploadRepository(filePath string) (UploadRepository, error) {
+	r := &fileUploadRepository{
+		uploads:  make(map[string]*models.Upload),
+		filePath: filePath,
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Create registra un nuevo upload en el inquilino de ctx
+func (r *fileUploadRepository) Create(ctx context.Context, upload *models.Upload) (*models.Upload, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	if r.closed {
+		return nil, errors.ErrShuttingDown
+	}
+
+	if _, exists := r.uploads[upload.ID]; exists {
+		return nil, errors.NewDatabaseError(fmt.Sprintf("ya existe un upload con ID %s", upload.ID))
+	}
+
+	stored := *upload
+	stored.Tenant = tenant.FromContext(ctx)
+	r.uploads[upload.ID] = &stored
+
+	if err := r.persist(); err != nil {
+		delete(r.uploads, upload.ID)
+		return nil, err
+	}
+
+	return r.copyOf(&stored), nil
+}
+
+// GetByID obtiene un upload por su ID
+func (r *fileUploadRepository) GetByID(ctx context.Context, id string) (*models.Upload, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+
+	upload, exists := r.uploads[id]
+	if !exists || !r.visible(ctx, upload) {
+		return nil, errors.ErrUploadNotFound
+	}
+
+	return r.copyOf(upload), nil
+}
+
+// GetByStoredName obtiene un upload por el nombre con el que se almacenó
+func (r *fileUploadRepository) GetByStoredName(ctx context.Context, storedName string) (*models.Upload, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+
+	for _, upload := range r.uploads {
+		if upload.StoredName == storedName && r.visible(ctx, upload) {
+			return r.copyOf(upload), nil
+		}
+	}
+
+	return nil, errors.ErrUploadNotFound
+}
+
+// GetAll obtiene todos los uploads, del más reciente al más antiguo
+func (r *fileUploadRepository) GetAll(ctx context.Context) ([]*models.Upload, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+
+	uploads := make([]*models.Upload, 0)
+	for _, upload := range r.uploads {
+		if r.visible(ctx, upload) {
+			uploads = append(uploads, r.copyOf(upload))
+		}
+	}
+
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].UploadedAt.After(uploads[j].UploadedAt)
+	})
+
+	return uploads, nil
+}
+
+// Update actualiza un upload existente
+func (r *fileUploadRepository) Update(ctx context.Context, upload *models.Upload) (*models.Upload, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, errors.NewContextError(err)
+	}
+	if r.closed {
+		return nil, errors.ErrShuttingDown
+	}
+
+	previous, exists := r.uploads[upload.ID]
+	if !exists || !r.visible(ctx, previous) {
+		return nil, errors.ErrUploadNotFound
+	}
+
+	// Un upload no puede cambiar de inquilino
+	stored := *upload
+	stored.Tenant = previous.Tenant
+	r.uploads[upload.ID] = &stored
+
+	if err := r.persist(); err != nil {
+		r.uploads[upload.ID] = previous
+		return nil, err
+	}
+
+	return r.copyOf(&stored), nil
+}
+
+// Delete elimina un upload del registro
+func (r *fileUploadRepository) Delete(ctx context.Context, id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return errors.NewContextError(err)
+	}
+	if r.closed {
+		return errors.ErrShuttingDown
+	}
+
+	previous, exists := r.uploads[id]
+	if !exists || !r.visible(ctx, previous) {
+		return errors.ErrUploadNotFound
+	}
+
+	delete(r.uploads, id)
+
+	if err := r.persist(); err != nil {
+		r.uploads[id] = previous
+		return err
+	}
+
+	return nil
+}
+
+// Close guarda el registro en disco, asegurando que llegue al dispositivo
+func (r *fileUploadRepository) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	if err := r.persist(); err != nil {
+		return err
+	}
+	if err := syncFile(r.filePath); err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("no se pudo guardar el registro de uploads: %v", err))
+	}
+
+	return nil
+}
+
+// Métodos auxiliares privados
+
+// visible indica si el upload pertenece al inquilino de ctx
+func (r *fileUploadRepository) visible(ctx context.Context, upload *models.Upload) bool {
+	return upload.Tenant == tenant.FromContext(ctx)
+}
+
+// copyOf devuelve una copia para que los llamadores no modifiquen el registro
+func (r *fileUploadRepository) copyOf(upload *models.Upload) *models.Upload {
+	c := *upload
+	return &c
+}
+
+// load carga el registro desde disco si el archivo existe
+func (r *fileUploadRepository) load() error {
+	data, err := os.ReadFile(r.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("no se pudo leer el registro de uploads: %v", err))
+	}
+
+	var uploads []*models.Upload
+	if err := json.Unmarshal(data, &uploads); err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("registro de uploads corrupto: %v", err))
+	}
+
+	for _, upload := range uploads {
+		// Los registros anteriores a los inquilinos pertenecen al inquilino por defecto
+		upload.Tenant = tenant.Normalize(upload.Tenant)
+		r.uploads[upload.ID] = upload
+	}
+
+	return nil
+}
+
+// persist escribe el registro completo a disco (requiere el lock de escritura).
+// Se escribe a un archivo temporal y se renombra para no dejar archivos a medias.
+func (r *fileUploadRepository) persist() error {
+	uploads := make([]*models.Upload, 0, len(r.uploads))
+	for _, upload := range r.uploads {
+		uploads = append(uploads, upload)
+	}
+
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].UploadedAt.Before(uploads[j].UploadedAt)
+	})
+
+	data, err := json.MarshalIndent(uploads, "", "  ")
+	if err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("no se pudo serializar el registro de uploads: %v", err))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.filePath), 0755); err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("no se pudo crear el directorio del registro: %v", err))
+	}
+
+	tmpPath := r.filePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("no se pudo escribir el registro de uploads: %v", err))
+	}
+
+	if err := os.Rename(tmpPath, r.filePath); err != nil {
+		os.Remove(tmpPath)
+		return errors.NewDatabaseError(fmt.Sprintf("no se pudo guardar el registro de uploads: %v", err))
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/utils"
+	"sort"
+)
+
+// idSet conjunto de IDs de clientes
+type idSet map[int]struct{}
+
+// clientIndex índices de los clientes de un dataset. Permite obtener un
+// cliente por ID y buscar por clave, correo o teléfono normalizados sin
+// recorrer todo el dataset. Lo mantiene el dataset bajo su propio lock.
+type clientIndex struct {
+	// position posición de cada cliente en dataset.clients, por ID
+	position map[int]int
+	byClave  map[string]idSet
+	byEmail  map[string]idSet
+	byPhone  map[string]idSet
+}
+
+// newClientIndex construye los índices de clients
+func newClientIndex(clients []*models.Client) *clientIndex {
+	idx := &clientIndex{
+		position: make(map[int]int, len(clients)),
+		byClave:  make(map[string]idSet),
+		byEmail:  make(map[string]idSet),
+		byPhone:  make(map[string]idSet),
+	}
+
+	for i, client := range clients {
+		idx.add(client, i)
+	}
+
+	return idx
+}
+
+// add indexa un cliente en la posición pos
+func (idx *clientIndex) add(client *models.Client, pos int) {
+	idx.position[client.ID] = pos
+	addToSet(idx.byClave, client.Clave, client.ID)
+	addToSet(idx.byEmail, utils.NormalizeEmail(client.Correo), client.ID)
+	addToSet(idx.byPhone, utils.NormalizePhone(client.Telefono), client.ID)
+}
+
+// remove elimina un cliente de los índices
+func (idx *clientIndex) remove(client *models.Client) {
+	delete(idx.position, client.ID)
+	removeFromSet(idx.byClave, client.Clave, client.ID)
+	removeFromSet(idx.byEmail, utils.NormalizeEmail(client.Correo), client.ID)
+	removeFromSet(idx.byPhone, utils.NormalizePhone(client.Telefono), client.ID)
+}
+
+// claveTakenByOther indica si otro cliente distinto de exceptID usa la clave
+func (idx *clientIndex) claveTakenByOther(clave string, exceptID int) bool {
+	ids := idx.byClave[clave]
+	if _, self := ids[exceptID]; self {
+		return len(ids) > 1
+	}
+	return len(ids) > 0
+}
+
+// duplicateClaves devuelve los IDs de los clientes de cada clave repetida
+func (idx *clientIndex) duplicateClaves() map[string][]int {
+	duplicates := make(map[string][]int)
+	for clave, ids := range idx.byClave {
+		if len(ids) > 1 {
+			duplicates[clave] = ids.sorted()
+		}
+	}
+	return duplicates
+}
+
+// sorted devuelve los IDs del conjunto en orden ascendente
+func (s idSet) sorted() []int {
+	ids := make([]int, 0, len(s))
+	for id := range s {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func addToSet(index map[string]idSet, key string, id int) {
+	if key == "" {
+		return
+	}
+	ids, exists := index[key]
+	if !exists {
+		ids = make(idSet, 1)
+		index[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func removeFromSet(index map[string]idSet, key string, id int) {
+	ids, exists := index[key]
+	if !exists {
+		return
+	}
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(index, key)
+	}
+}
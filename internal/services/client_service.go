@@ -1,218 +1,497 @@
 package services
 
 import (
+	"bytes"
 	"client-data-compiler/internal/domain/errors"
 	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/storage"
+	"client-data-compiler/internal/tenant"
+	"client-data-compiler/internal/utils"
+	"context"
+	stderrors "errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"path"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
 type ClientService interface {
-	LoadClientsFromExcel(filePath string) ([]*models.Client, error)
-	GetClients(filter *models.ClientFilter) ([]*models.Client, error)
-	GetClientByID(id int) (*models.Client, error)
-	UpdateClient(id int, client *models.Client) (*models.Client, error)
-	DeleteClient(id int) error
-	ValidateAllClients() ([]*models.Client, error)
-	ValidateClient(client *models.Client) *models.Client
-	ExportClientsToExcel(filename string) (string, error)
-	GetStats() (*models.ClientStats, error)
-	ClearAllClients() error
-	GetClientCount() int
+	LoadClientsFromExcel(ctx context.Context, datasetName, filePath, uploadID string, progress ProgressReporter) (*ParsedClients, error)
+	ParseClientsFromExcel(ctx context.Context, filePath, uploadID string, progress ProgressReporter) (*ParsedClients, error)
+	StoreClients(ctx context.Context, datasetName string, parsed *ParsedClients, progress ProgressReporter) error
+	GetClients(ctx context.Context, datasetName string, filter *models.ClientFilter) (*models.ClientPage, error)
+	GetClientByID(ctx context.Context, datasetName string, id int) (*models.Client, error)
+	LookupClients(ctx context.Context, datasetName, field, value string) ([]*models.Client, error)
+	SearchClients(ctx context.Context, datasetName, query string, limit int) ([]*models.ClientSearchResult, int, error)
+	UpdateClient(ctx context.Context, datasetName string, id int, client *models.Client) (*models.Client, error)
+	DeleteClient(ctx context.Context, datasetName string, id int) error
+	DeleteClients(ctx context.Context, datasetName string, where models.ClientMatcher, dryRun bool) (int, error)
+	ValidateAllClients(ctx context.Context, datasetName string, progress ProgressReporter) ([]*models.Client, error)
+	ValidateClient(ctx context.Context, client *models.Client) *models.Client
+	ExportClientsToExcel(ctx context.Context, datasetName, filename string, filter *models.ClientFilter) (*models.ExportedFile, error)
+	GetExport(ctx context.Context, id string) (*storage.Object, error)
+	GetStats(ctx context.Context, datasetName string, filter *models.ClientFilter) (*models.ClientStats, error)
+	ClearAllClients(ctx context.Context, datasetName string) error
+	GetClientCount(ctx context.Context, datasetName string) int
+	HasClientsFromUpload(ctx context.Context, datasetName, uploadID string) bool
+
+	ListDatasets(ctx context.Context) []*models.Dataset
+	GetDataset(ctx context.Context, name string) (*models.Dataset, error)
+	RenameDataset(ctx context.Context, name, newName string) (*models.Dataset, error)
+	DeleteDataset(ctx context.Context, name string) error
 }
 
+// defaultCursorLimit tamaño de página en la paginación por cursor si no se indica limit
+const defaultCursorLimit = 50
+
 type clientService struct {
-	clients           []*models.Client
+	// datasets datasets de todos los inquilinos; cada operación solo accede a
+	// los del inquilino de su contexto
+	datasets          map[datasetKey]*dataset
 	mu                sync.RWMutex
 	excelService      ExcelService
 	validationService ValidationService
-	lastID            int
+	// store almacén de los archivos exportados
+	store storage.Storage
+}
+
+// previewSize clientes de la vista previa de una importación
+const previewSize = 5
+
+// ParsedClients resultado de leer un archivo: los clientes se indexan por
+// bloques a medida que se leen, de modo que StoreClients solo tiene que
+// reemplazar los del dataset
+type ParsedClients struct {
+	Total   int
+	Valid   int
+	Invalid int
+	// Preview primeros clientes leídos
+	Preview []*models.Client
+	Timings *models.StageTimings
+	staged  *dataset
 }
 
-func NewClientService(excelService ExcelService, validationService ValidationService) ClientService {
+func NewClientService(excelService ExcelService, validationService ValidationService, store storage.Storage) ClientService {
 	return &clientService{
-		clients:           make([]*models.Client, 0),
+		datasets: map[datasetKey]*dataset{
+			{tenant: tenant.Default, name: models.DefaultDatasetName}: newDataset(models.DefaultDatasetName),
+		},
 		excelService:      excelService,
 		validationService: validationService,
-		lastID:            0,
+		store:             store,
 	}
 }
 
-// LoadClientsFromExcel carga clientes desde un archivo Excel
-func (s *clientService) LoadClientsFromExcel(filePath string) ([]*models.Client, error) {
-	// Validar estructura del archivo
-	if err := s.excelService.ValidateExcelStructure(filePath); err != nil {
+// LoadClientsFromExcel carga clientes desde un archivo Excel en el dataset indicado,
+// creándolo si no existe. uploadID identifica el upload que originó los clientes.
+// Si ctx se cancela antes de terminar, el dataset no se modifica.
+func (s *clientService) LoadClientsFromExcel(ctx context.Context, datasetName, filePath, uploadID string, progress ProgressReporter) (*ParsedClients, error) {
+	if err := ValidateDatasetName(datasetName); err != nil {
 		return nil, err
 	}
 
-	// Leer archivo Excel
-	clients, err := s.excelService.ReadExcelFile(filePath)
+	parsed, err := s.ParseClientsFromExcel(ctx, filePath, uploadID, progress)
 	if err != nil {
 		return nil, err
 	}
 
-	// Validar clientes
-	clients = s.validationService.ValidateClientsConcurrent(clients)
+	if err := s.StoreClients(ctx, datasetName, parsed, progress); err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// ParseClientsFromExcel lee y valida los clientes de un archivo Excel sin
+// almacenarlos, junto con el tiempo de cada etapa. uploadID identifica el
+// upload que originó los clientes.
+func (s *clientService) ParseClientsFromExcel(ctx context.Context, filePath, uploadID string, progress ProgressReporter) (*ParsedClients, error) {
+	progress = progressOrNoop(progress)
+	parsed := &ParsedClients{Timings: &models.StageTimings{}, staged: newDataset("")}
+	start := time.Now()
+
+	// Leer, validar, detectar claves duplicadas e indexar por bloques, sin
+	// cargar la hoja completa en memoria
+	var validateTime, duplicatesTime time.Duration
+	firstByKey := make(map[string]*models.Client)
+
+	err := s.excelService.StreamExcelFile(ctx, filePath, streamBatchSize, progress, func(batch []*models.Client) error {
+		// Vincular clientes con el upload de origen
+		for _, client := range batch {
+			client.SourceUpload = uploadID
+		}
+
+		validateStart := time.Now()
+		if err := s.validationService.ValidateBatch(ctx, batch, progress); err != nil {
+			return err
+		}
+		validateTime += time.Since(validateStart)
+
+		duplicatesStart := time.Now()
+		markDuplicateKeys(firstByKey, batch)
+		duplicatesTime += time.Since(duplicatesStart)
 
-	// Verificar claves duplicadas
-	s.checkDuplicateKeys(clients)
+		parsed.staged.appendClients(batch)
+		if missing := previewSize - len(parsed.Preview); missing > 0 {
+			parsed.Preview = append(parsed.Preview, batch[:min(missing, len(batch))]...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	progress.Stage(models.StageDuplicatesChecked)
+
+	// Se cuentan al final porque una clave duplicada en un bloque posterior
+	// invalida también al primer cliente con esa clave
+	parsed.Total = len(parsed.staged.clients)
+	for _, client := range parsed.staged.clients {
+		if client.IsValid {
+			parsed.Valid++
+		} else {
+			parsed.Invalid++
+		}
+	}
+
+	parsed.Timings.ValidateMs = validateTime.Milliseconds()
+	parsed.Timings.DuplicatesMs = duplicatesTime.Milliseconds()
+	parsed.Timings.ParseMs = (time.Since(start) - validateTime - duplicatesTime).Milliseconds()
+
+	return parsed, nil
+}
+
+// StoreClients reemplaza los clientes del dataset indicado por los leídos en
+// parsed, creándolo si no existe. Si ctx ya terminó, el dataset no se modifica.
+func (s *clientService) StoreClients(ctx context.Context, datasetName string, parsed *ParsedClients, progress ProgressReporter) error {
+	progress = progressOrNoop(progress)
+
+	if ctx.Err() != nil {
+		return errors.NewContextError(ctx.Err())
+	}
 
 	// Almacenar en memoria
-	s.mu.Lock()
-	s.clients = clients
-	s.updateLastID()
-	s.mu.Unlock()
+	progress.Stage(models.StageStoring)
+	ds, err := s.getOrCreateDataset(ctx, datasetName)
+	if err != nil {
+		return err
+	}
 
-	return clients, nil
+	ds.mu.Lock()
+	ds.replaceContents(parsed.staged)
+	ds.touch()
+	ds.mu.Unlock()
+
+	return nil
 }
 
-// GetClients obtiene clientes con filtros opcionales
-func (s *clientService) GetClients(filter *models.ClientFilter) ([]*models.Client, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// GetClients obtiene una página de clientes con filtros opcionales,
+// deteniéndose si ctx termina. Total es el número de clientes que cumplen el
+// filtro. Con filter.Cursor la página empieza justo después (o termina justo
+// antes) del cliente indicado por el cursor, en lugar de usar filter.Page.
+func (s *clientService) GetClients(ctx context.Context, datasetName string, filter *models.ClientFilter) (*models.ClientPage, error) {
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return nil, err
+	}
 
 	if filter == nil {
-		return s.clients, nil
+		filter = &models.ClientFilter{}
+	}
+
+	sortFields := filter.Sort
+	var cursor *models.ClientCursor
+	if filter.Cursor != "" {
+		cursor, err = models.DecodeClientCursor(filter.Cursor)
+		if err != nil {
+			return nil, errors.ErrInvalidCursor
+		}
+
+		// Sin ordenamiento explícito se usa el del cursor; si se indica, debe coincidir
+		if len(sortFields) == 0 {
+			if sortFields, err = models.ParseSortFields(cursor.Sort); err != nil {
+				return nil, errors.ErrInvalidCursor
+			}
+		} else if models.FormatSortFields(sortFields) != cursor.Sort {
+			return nil, errors.ErrInvalidCursor
+		}
 	}
 
 	// Aplicar filtros
+	ds.mu.RLock()
 	filteredClients := make([]*models.Client, 0)
-
-	for _, client := range s.clients {
-		if s.matchesFilter(client, filter) {
+	for i, client := range ds.clients {
+		if i%progressBatchSize == 0 && ctx.Err() != nil {
+			ds.mu.RUnlock()
+			return nil, errors.NewContextError(ctx.Err())
+		}
+		if client != nil && s.matchesFilter(client, filter) {
 			filteredClients = append(filteredClients, client)
 		}
 	}
+	ds.mu.RUnlock()
 
-	// Aplicar paginación
-	if filter.Page > 0 && filter.Limit > 0 {
-		start := (filter.Page - 1) * filter.Limit
-		end := start + filter.Limit
+	// Ordenar antes de paginar
+	models.SortClients(filteredClients, sortFields)
 
-		if start >= len(filteredClients) {
-			return []*models.Client{}, nil
-		}
+	total := len(filteredClients)
+	page := &models.ClientPage{
+		Total: total,
+		Page:  filter.Page,
+		Limit: filter.Limit,
+	}
 
-		if end > len(filteredClients) {
-			end = len(filteredClients)
+	// Aplicar paginación
+	start, end := 0, total
+	switch {
+	case cursor != nil:
+		page.Page = 0
+		if page.Limit <= 0 {
+			page.Limit = defaultCursorLimit
 		}
+		start, end = cursorBounds(filteredClients, cursor, sortFields, page.Limit)
+	case filter.Limit > 0:
+		// Con límite y sin página se devuelve la primera
+		page.Page = max(filter.Page, 1)
+		start = min((page.Page-1)*filter.Limit, total)
+		end = min(start+filter.Limit, total)
+	}
+
+	page.Clients = filteredClients[start:end]
 
-		return filteredClients[start:end], nil
+	if page.Limit > 0 {
+		page.TotalPages = (total + page.Limit - 1) / page.Limit
+	} else if total > 0 {
+		page.TotalPages = 1
 	}
 
-	return filteredClients, nil
+	// Cursores hacia las páginas vecinas
+	if start < end {
+		if end < total {
+			page.NextCursor = models.NewClientCursor(page.Clients[len(page.Clients)-1], sortFields, false).Encode()
+		}
+		if start > 0 {
+			page.PrevCursor = models.NewClientCursor(page.Clients[0], sortFields, true).Encode()
+		}
+	}
+
+	return page, nil
 }
 
 // GetClientByID obtiene un cliente por su ID
-func (s *clientService) GetClientByID(id int) (*models.Client, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+func (s *clientService) GetClientByID(ctx context.Context, datasetName string, id int) (*models.Client, error) {
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, client := range s.clients {
-		if client.ID == id {
-			return client, nil
-		}
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	client, _, exists := ds.clientByID(id)
+	if !exists {
+		return nil, errors.ErrClientNotFound
 	}
 
-	return nil, errors.ErrClientNotFound
+	return client, nil
 }
 
-// UpdateClient actualiza un cliente existente
-func (s *clientService) UpdateClient(id int, updatedClient *models.Client) (*models.Client, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// LookupClients busca por coincidencia exacta en un campo indexado: "clave",
+// "correo" (sin distinguir mayúsculas) o "telefono" (solo dígitos)
+func (s *clientService) LookupClients(ctx context.Context, datasetName, field, value string) ([]*models.Client, error) {
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return nil, err
+	}
 
-	// Buscar cliente
-	clientIndex := -1
-	for i, client := range s.clients {
-		if client.ID == id {
-			clientIndex = i
-			break
-		}
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	return ds.lookup(field, value)
+}
+
+// SearchClients busca clientes por texto libre sin distinguir mayúsculas ni
+// acentos. Cada palabra debe aparecer, completa o como prefijo, en algún campo
+// del cliente. Devuelve hasta limit resultados (todos si limit <= 0)
+// ordenados por relevancia, y el total de coincidencias.
+func (s *clientService) SearchClients(ctx context.Context, datasetName, query string, limit int) ([]*models.ClientSearchResult, int, error) {
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	if clientIndex == -1 {
+	ds.mu.RLock()
+	results := ds.searchClients(query)
+	ds.mu.RUnlock()
+
+	total := len(results)
+	if limit > 0 && limit < total {
+		results = results[:limit]
+	}
+
+	return results, total, nil
+}
+
+// UpdateClient actualiza un cliente existente
+func (s *clientService) UpdateClient(ctx context.Context, datasetName string, id int, updatedClient *models.Client) (*models.Client, error) {
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	// Buscar cliente
+	originalClient, clientIndex, exists := ds.clientByID(id)
+	if !exists {
 		return nil, errors.ErrClientNotFound
 	}
 
 	// Verificar clave duplicada (excluyendo el cliente actual)
-	for i, client := range s.clients {
-		if i != clientIndex && client.Clave == updatedClient.Clave {
-			return nil, errors.ErrDuplicateClientKey
-		}
+	if ds.index.claveTakenByOther(updatedClient.Clave, id) {
+		return nil, errors.ErrDuplicateClientKey
 	}
 
 	// Mantener datos originales
-	originalClient := s.clients[clientIndex]
 	updatedClient.ID = originalClient.ID
 	updatedClient.RowNumber = originalClient.RowNumber
+	updatedClient.SourceUpload = originalClient.SourceUpload
 	updatedClient.CreatedAt = originalClient.CreatedAt
 	updatedClient.UpdatedAt = time.Now()
 
 	// Validar cliente actualizado
-	validatedClient := s.validationService.ValidateClient(updatedClient)
+	validatedClient := s.validationService.ValidateClient(ctx, updatedClient)
 
 	// Actualizar en memoria
-	s.clients[clientIndex] = validatedClient
+	ds.replaceClient(clientIndex, validatedClient)
+	ds.touch()
 
 	return validatedClient, nil
 }
 
 // DeleteClient elimina un cliente
-func (s *clientService) DeleteClient(id int) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	clientIndex := -1
-	for i, client := range s.clients {
-		if client.ID == id {
-			clientIndex = i
-			break
-		}
+func (s *clientService) DeleteClient(ctx context.Context, datasetName string, id int) error {
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return err
 	}
 
-	if clientIndex == -1 {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	_, clientIndex, exists := ds.clientByID(id)
+	if !exists {
 		return errors.ErrClientNotFound
 	}
 
 	// Eliminar cliente
-	s.clients = append(s.clients[:clientIndex], s.clients[clientIndex+1:]...)
+	ds.removeClient(clientIndex)
+	ds.touch()
 
 	return nil
 }
 
-// ValidateAllClients valida todos los clientes cargados
-func (s *clientService) ValidateAllClients() ([]*models.Client, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// DeleteClients elimina los clientes que cumplen where y devuelve cuántos
+// eran. Con dryRun solo los cuenta, sin eliminarlos.
+func (s *clientService) DeleteClients(ctx context.Context, datasetName string, where models.ClientMatcher, dryRun bool) (int, error) {
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return 0, err
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
 
-	// Validar todos los clientes
-	s.clients = s.validationService.ValidateClientsConcurrent(s.clients)
+	kept := make([]*models.Client, 0, ds.count())
+	for i, client := range ds.clients {
+		if i%progressBatchSize == 0 && ctx.Err() != nil {
+			return 0, errors.NewContextError(ctx.Err())
+		}
+		if client != nil && !where.Match(client) {
+			kept = append(kept, client)
+		}
+	}
+
+	deleted := ds.count() - len(kept)
+	if dryRun || deleted == 0 {
+		return deleted, nil
+	}
 
-	// Verificar claves duplicadas
-	s.checkDuplicateKeys(s.clients)
+	ds.removeClients(kept)
+	ds.touch()
+
+	return deleted, nil
+}
+
+// ValidateAllClients valida todos los clientes cargados, reportando el avance.
+// Si ctx se cancela a mitad de la validación, los clientes ya revalidados
+// conservan su nuevo estado.
+func (s *clientService) ValidateAllClients(ctx context.Context, datasetName string, progress ProgressReporter) ([]*models.Client, error) {
+	progress = progressOrNoop(progress)
+
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	// Validar todos los clientes
+	ds.compact()
+	_, validationErr := s.validationService.ValidateClientsConcurrent(ctx, ds.clients, progress)
+
+	// Verificar claves duplicadas (también tras una cancelación, porque la
+	// validación limpia las marcas de duplicado de los clientes revalidados)
+	progress.Stage(models.StageCheckingDuplicates)
+	ds.reindex()
+	s.markIndexedDuplicates(ds)
+	progress.Stage(models.StageDuplicatesChecked)
+	ds.touch()
+
+	if validationErr != nil {
+		return nil, validationErr
+	}
 
-	return s.clients, nil
+	// Copia tomada con el lock: tras liberarlo, un borrado concurrente deja
+	// huecos en ds.clients y una compactación reordena sus elementos
+	return slices.Clone(ds.clients), nil
 }
 
-// ValidateClient valida un cliente individual
-func (s *clientService) ValidateClient(client *models.Client) *models.Client {
-	return s.validationService.ValidateClient(client)
+// ValidateClient valida un cliente individual con las reglas del inquilino de ctx
+func (s *clientService) ValidateClient(ctx context.Context, client *models.Client) *models.Client {
+	return s.validationService.ValidateClient(ctx, client)
 }
 
-// ExportClientsToExcel exporta los clientes a un archivo Excel
-func (s *clientService) ExportClientsToExcel(filename string) (string, error) {
-	s.mu.RLock()
-	clients := make([]*models.Client, len(s.clients))
-	copy(clients, s.clients)
-	s.mu.RUnlock()
+// ExportClientsToExcel exporta a un archivo Excel los clientes que cumplen el
+// filtro (todos si es nil), en el orden que indique filter.Sort. La
+// paginación del filtro se ignora. El archivo se almacena con un ID opaco
+// entre las exportaciones del inquilino; filename es solo el nombre con el
+// que se descarga.
+func (s *clientService) ExportClientsToExcel(ctx context.Context, datasetName, filename string, filter *models.ClientFilter) (*models.ExportedFile, error) {
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.mu.RLock()
+	clients := make([]*models.Client, 0, ds.count())
+	for _, client := range ds.clients {
+		if client != nil && (filter == nil || s.matchesFilter(client, filter)) {
+			clients = append(clients, client)
+		}
+	}
+	ds.mu.RUnlock()
+
+	if filter != nil {
+		models.SortClients(clients, filter.Sort)
+	}
 
 	if len(clients) == 0 {
-		return "", errors.NewFileProcessingError("No hay clientes para exportar")
+		return nil, errors.NewFileProcessingError("No hay clientes para exportar")
 	}
 
 	// Generar nombre de archivo único si no se proporciona
@@ -222,34 +501,74 @@ func (s *clientService) ExportClientsToExcel(filename string) (string, error) {
 	}
 
 	// Asegurar que termine en .xlsx
+	filename = utils.SanitizeFilename(filename)
 	if !strings.HasSuffix(strings.ToLower(filename), ".xlsx") {
 		filename += ".xlsx"
 	}
 
-	// Ruta completa del archivo
-	filePath := filepath.Join("uploads", filename)
+	export := &models.ExportedFile{
+		ID:       utils.GenerateID(),
+		Filename: filename,
+	}
 
 	// Exportar a Excel
-	if err := s.excelService.WriteExcelFile(clients, filePath); err != nil {
-		return "", err
+	var buf bytes.Buffer
+	if err := s.excelService.WriteExcel(ctx, clients, &buf); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Put(ctx, exportKey(ctx, export.ID), &buf, int64(buf.Len())); err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewContextError(ctx.Err())
+		}
+		return nil, errors.NewFileProcessingError(fmt.Sprintf("Error guardando archivo: %v", err))
+	}
+
+	return export, nil
+}
+
+// GetExport abre el archivo de una exportación del inquilino de ctx; el
+// llamador debe cerrarlo
+func (s *clientService) GetExport(ctx context.Context, id string) (*storage.Object, error) {
+	if !utils.IsGeneratedID(id) {
+		return nil, errors.ErrExportNotFound
+	}
+
+	object, err := s.store.Get(ctx, exportKey(ctx, id))
+	if stderrors.Is(err, storage.ErrNotFound) {
+		return nil, errors.ErrExportNotFound
+	}
+	if err != nil {
+		return nil, errors.NewFileProcessingError(fmt.Sprintf("Error abriendo archivo: %v", err))
 	}
 
-	return filePath, nil
+	return object, nil
 }
 
-// GetStats obtiene estadísticas de los clientes
-func (s *clientService) GetStats() (*models.ClientStats, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// GetStats obtiene estadísticas de los clientes que cumplen el filtro (todos
+// si es nil)
+func (s *clientService) GetStats(ctx context.Context, datasetName string, filter *models.ClientFilter) (*models.ClientStats, error) {
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
 
 	stats := &models.ClientStats{
-		Total:         len(s.clients),
+		Total:         0,
 		Valid:         0,
 		Invalid:       0,
 		ErrorsByField: make(map[string]int),
 	}
 
-	for _, client := range s.clients {
+	for _, client := range ds.clients {
+		if client == nil || (filter != nil && !s.matchesFilter(client, filter)) {
+			continue
+		}
+
+		stats.Total++
 		if client.IsValid {
 			stats.Valid++
 		} else {
@@ -265,27 +584,62 @@ func (s *clientService) GetStats() (*models.ClientStats, error) {
 	return stats, nil
 }
 
-// ClearAllClients limpia todos los clientes de la memoria
-func (s *clientService) ClearAllClients() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// ClearAllClients limpia todos los clientes del dataset
+func (s *clientService) ClearAllClients(ctx context.Context, datasetName string) error {
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return err
+	}
 
-	s.clients = make([]*models.Client, 0)
-	s.lastID = 0
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ds.setClients(make([]*models.Client, 0))
+	ds.touch()
 
 	return nil
 }
 
-// GetClientCount obtiene el número total de clientes
-func (s *clientService) GetClientCount() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// GetClientCount obtiene el número total de clientes del dataset
+func (s *clientService) GetClientCount(ctx context.Context, datasetName string) int {
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return 0
+	}
+
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	return ds.count()
+}
+
+// HasClientsFromUpload indica si el dataset conserva algún cliente importado
+// por el upload indicado. Es false si el dataset no existe.
+func (s *clientService) HasClientsFromUpload(ctx context.Context, datasetName, uploadID string) bool {
+	ds, err := s.getDataset(ctx, datasetName)
+	if err != nil {
+		return false
+	}
+
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+
+	for _, client := range ds.clients {
+		if client != nil && client.SourceUpload == uploadID {
+			return true
+		}
+	}
 
-	return len(s.clients)
+	return false
 }
 
 // Métodos auxiliares privados
 
+// exportKey clave de almacenamiento de una exportación del inquilino de ctx
+func exportKey(ctx context.Context, id string) string {
+	return path.Join(tenant.Prefix(uploadsPrefix, tenant.FromContext(ctx)), "exports", id+".xlsx")
+}
+
 // matchesFilter verifica si un cliente coincide con los filtros
 func (s *clientService) matchesFilter(client *models.Client, filter *models.ClientFilter) bool {
 	// Filtro por clave
@@ -324,55 +678,59 @@ func (s *clientService) matchesFilter(client *models.Client, filter *models.Clie
 		}
 	}
 
+	// Expresión de filtro
+	if filter.Where != nil && !filter.Where.Match(client) {
+		return false
+	}
+
 	return true
 }
 
-// checkDuplicateKeys verifica y marca claves duplicadas
-func (s *clientService) checkDuplicateKeys(clients []*models.Client) {
-	keyCount := make(map[string][]int)
-
-	// Contar ocurrencias de cada clave
-	for i, client := range clients {
-		if client.Clave != "" {
-			keyCount[client.Clave] = append(keyCount[client.Clave], i)
-		}
+// cursorBounds obtiene los límites de la página de tamaño limit que sigue al
+// cliente del cursor (o lo precede, si cursor.Before) en clients, ya ordenados
+func cursorBounds(clients []*models.Client, cursor *models.ClientCursor, fields []models.SortField, limit int) (int, int) {
+	compareAt := func(i int) int {
+		key := clients[i].SortKey()
+		return models.CompareSortKeys(&key, &cursor.Key, fields)
 	}
 
-	// Marcar duplicados
-	for key, indices := range keyCount {
-		if len(indices) > 1 {
-			for _, index := range indices {
-				clients[index].AddError("clave", fmt.Sprintf("Clave duplicada: %s", key))
-			}
-		}
+	if cursor.Before {
+		end := sort.Search(len(clients), func(i int) bool { return compareAt(i) >= 0 })
+		return max(end-limit, 0), end
 	}
-}
 
-// updateLastID actualiza el último ID usado
-func (s *clientService) updateLastID() {
-	maxID := 0
-	for _, client := range s.clients {
-		if client.ID > maxID {
-			maxID = client.ID
-		}
-	}
-	s.lastID = maxID
+	start := sort.Search(len(clients), func(i int) bool { return compareAt(i) > 0 })
+	return start, min(start+limit, len(clients))
 }
 
-// CleanupTempFiles limpia archivos temporales antiguos
-func (s *clientService) CleanupTempFiles() error {
-	uploadsDir := "uploads"
-
-	return filepath.Walk(uploadsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Continuar con otros archivos
+// markDuplicateKeys marca las claves de batch que ya aparecieron en bloques
+// anteriores o en el mismo bloque. firstByKey guarda el primer cliente de cada
+// clave, que también se marca al encontrar su primer duplicado.
+func markDuplicateKeys(firstByKey map[string]*models.Client, batch []*models.Client) {
+	for _, client := range batch {
+		if client.Clave == "" {
+			continue
 		}
 
-		// Eliminar archivos más antiguos de 24 horas
-		if time.Since(info.ModTime()) > 24*time.Hour {
-			os.Remove(path)
+		first, exists := firstByKey[client.Clave]
+		if !exists {
+			firstByKey[client.Clave] = client
+			continue
 		}
 
-		return nil
-	})
+		message := fmt.Sprintf("Clave duplicada: %s", client.Clave)
+		first.AddError("clave", message)
+		client.AddError("clave", message)
+	}
+}
+
+// markIndexedDuplicates marca las claves duplicadas usando el índice del
+// dataset (requiere el lock de escritura)
+func (s *clientService) markIndexedDuplicates(ds *dataset) {
+	for key, ids := range ds.index.duplicateClaves() {
+		for _, id := range ids {
+			client, _, _ := ds.clientByID(id)
+			client.AddError("clave", fmt.Sprintf("Clave duplicada: %s", key))
+		}
+	}
 }
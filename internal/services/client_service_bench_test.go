@@ -0,0 +1,161 @@
+package services
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/tenant"
+	"client-data-compiler/internal/utils"
+	"context"
+	"fmt"
+	"testing"
+)
+
+// benchmarkSizes tamaños de dataset de los benchmarks. Las operaciones sobre
+// un cliente deben costar lo mismo en todos.
+var benchmarkSizes = []int{1_000, 100_000, 1_000_000}
+
+// benchmarkDatasets datasets ya indexados por tamaño, compartidos por los
+// benchmarks que no cambian el número de clientes
+var benchmarkDatasets = map[int][]*models.Client{}
+
+func benchmarkClients(n int) []*models.Client {
+	nombres := []string{"Ana López", "José Pérez", "María Gómez", "Luis Hernández"}
+	clients := make([]*models.Client, n)
+	for i := range clients {
+		clients[i] = &models.Client{
+			ID:        i + 1,
+			Clave:     fmt.Sprintf("C%07d", i),
+			Nombre:    nombres[i%len(nombres)],
+			Correo:    fmt.Sprintf("cliente%d@gmail.com", i),
+			Telefono:  fmt.Sprintf("962%07d", i),
+			IsValid:   true,
+			RowNumber: i + 2,
+		}
+	}
+	return clients
+}
+
+// benchmarkService crea un servicio cuyo dataset por defecto tiene clients
+func benchmarkService(clients []*models.Client) (*clientService, *dataset) {
+	rules := models.ValidationRules{
+		Region:         utils.DefaultRegion,
+		PhoneAreaCodes: utils.DefaultPhoneAreaCodes,
+		EmailDomains:   utils.DefaultEmailDomains,
+	}
+	s := NewClientService(nil, NewValidationService(1, 100, rules, nil), nil).(*clientService)
+
+	ds := newDataset(models.DefaultDatasetName)
+	ds.setClients(clients)
+	s.datasets[datasetKey{tenant: tenant.Default, name: models.DefaultDatasetName}] = ds
+
+	return s, ds
+}
+
+// sharedBenchmarkService obtiene un servicio con un dataset de size clientes,
+// reutilizando el de benchmarks anteriores del mismo tamaño
+func sharedBenchmarkService(b *testing.B, size int) (*clientService, *dataset) {
+	if size >= 1_000_000 && testing.Short() {
+		b.Skip("dataset de un millón de clientes omitido con -short")
+	}
+
+	clients, exists := benchmarkDatasets[size]
+	if !exists {
+		clients = benchmarkClients(size)
+		benchmarkDatasets[size] = clients
+	}
+
+	b.StopTimer()
+	defer b.StartTimer()
+	return benchmarkService(clients)
+}
+
+func runSizes(b *testing.B, fn func(b *testing.B, size int)) {
+	for _, size := range benchmarkSizes {
+		b.Run(fmt.Sprintf("clientes=%d", size), func(b *testing.B) {
+			fn(b, size)
+		})
+	}
+}
+
+func BenchmarkGetClientByID(b *testing.B) {
+	runSizes(b, func(b *testing.B, size int) {
+		s, _ := sharedBenchmarkService(b, size)
+		ctx := context.Background()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := s.GetClientByID(ctx, models.DefaultDatasetName, i%size+1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkUpdateClient(b *testing.B) {
+	runSizes(b, func(b *testing.B, size int) {
+		s, _ := sharedBenchmarkService(b, size)
+		ctx := context.Background()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			n := i % size
+			updated := &models.Client{
+				Clave:    fmt.Sprintf("C%07d", n),
+				Nombre:   "Nombre Actualizado",
+				Correo:   fmt.Sprintf("actualizado%d@gmail.com", i),
+				Telefono: fmt.Sprintf("962%07d", n),
+			}
+			if _, err := s.UpdateClient(ctx, models.DefaultDatasetName, n+1, updated); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDeleteClient(b *testing.B) {
+	runSizes(b, func(b *testing.B, size int) {
+		if size >= 1_000_000 && testing.Short() {
+			b.Skip("dataset de un millón de clientes omitido con -short")
+		}
+
+		original := benchmarkClients(size)
+		s, ds := benchmarkService(append([]*models.Client(nil), original...))
+		ctx := context.Background()
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			// Restaurar el dataset cuando se ha eliminado la mitad, para medir
+			// siempre con un tamaño comparable
+			n := i % (size / 2)
+			if i > 0 && n == 0 {
+				b.StopTimer()
+				ds.setClients(append([]*models.Client(nil), original...))
+				b.StartTimer()
+			}
+
+			if err := s.DeleteClient(ctx, models.DefaultDatasetName, n*2+1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDuplicateClaveCheck(b *testing.B) {
+	runSizes(b, func(b *testing.B, size int) {
+		_, ds := sharedBenchmarkService(b, size)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			n := i % size
+			ds.mu.RLock()
+			taken := ds.index.claveTakenByOther(fmt.Sprintf("C%07d", n), n+2)
+			ds.mu.RUnlock()
+			if !taken {
+				b.Fatal("la clave debería estar en uso por otro cliente")
+			}
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"context"
+	"testing"
+)
+
+func TestGetClientsPagination(t *testing.T) {
+	s, _ := benchmarkService(benchmarkClients(120))
+
+	tests := []struct {
+		name       string
+		filter     *models.ClientFilter
+		page       int
+		limit      int
+		totalPages int
+		firstID    int
+		returned   int
+	}{
+		{name: "sin paginación", filter: &models.ClientFilter{}, page: 0, limit: 0, totalPages: 1, firstID: 1, returned: 120},
+		{name: "solo límite", filter: &models.ClientFilter{Limit: 50}, page: 1, limit: 50, totalPages: 3, firstID: 1, returned: 50},
+		{name: "página y límite", filter: &models.ClientFilter{Page: 2, Limit: 50}, page: 2, limit: 50, totalPages: 3, firstID: 51, returned: 50},
+		{name: "última página incompleta", filter: &models.ClientFilter{Page: 3, Limit: 50}, page: 3, limit: 50, totalPages: 3, firstID: 101, returned: 20},
+		{name: "página fuera de rango", filter: &models.ClientFilter{Page: 4, Limit: 50}, page: 4, limit: 50, totalPages: 3, returned: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := s.GetClients(context.Background(), models.DefaultDatasetName, tt.filter)
+			if err != nil {
+				t.Fatalf("GetClients: %v", err)
+			}
+
+			if page.Page != tt.page || page.Limit != tt.limit || page.TotalPages != tt.totalPages || page.Total != 120 {
+				t.Errorf("página %d, límite %d, %d páginas, total %d; se esperaba página %d, límite %d, %d páginas, total 120",
+					page.Page, page.Limit, page.TotalPages, page.Total, tt.page, tt.limit, tt.totalPages)
+			}
+			if len(page.Clients) != tt.returned {
+				t.Fatalf("%d clientes, se esperaban %d", len(page.Clients), tt.returned)
+			}
+			if tt.returned > 0 && page.Clients[0].ID != tt.firstID {
+				t.Errorf("primer cliente %d, se esperaba %d", page.Clients[0].ID, tt.firstID)
+			}
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package services
+
+import (
+	"client-data-compiler/internal/domain/errors"
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/tenant"
+	"client-data-compiler/internal/utils"
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// datasetNameRegex define los nombres de dataset permitidos
+var datasetNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+
+// dataset almacena en memoria los clientes de un conjunto de datos
+type dataset struct {
+	name string
+	// clients clientes en el orden del dataset. Los eliminados quedan como
+	// nil hasta la siguiente compactación, para que eliminar un cliente no
+	// desplace a los posteriores.
+	clients []*models.Client
+	// removed posiciones de clients que quedaron vacías
+	removed   int
+	index     *clientIndex
+	search    *searchIndex
+	mu        sync.RWMutex
+	lastID    int
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+func newDataset(name string) *dataset {
+	now := time.Now()
+	return &dataset{
+		name:      name,
+		clients:   make([]*models.Client, 0),
+		index:     newClientIndex(nil),
+		search:    newSearchIndex(nil),
+		createdAt: now,
+		updatedAt: now,
+	}
+}
+
+// info obtiene la representación pública del dataset
+func (d *dataset) info() *models.Dataset {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return &models.Dataset{
+		Name:        d.name,
+		ClientCount: d.count(),
+		CreatedAt:   d.createdAt,
+		UpdatedAt:   d.updatedAt,
+	}
+}
+
+// count obtiene el número de clientes del dataset (requiere el lock de lectura)
+func (d *dataset) count() int {
+	return len(d.clients) - d.removed
+}
+
+// touch marca el dataset como modificado (requiere el lock de escritura)
+func (d *dataset) touch() {
+	d.updatedAt = time.Now()
+}
+
+// setClients reemplaza los clientes del dataset y reconstruye sus índices
+// (requiere el lock de escritura)
+func (d *dataset) setClients(clients []*models.Client) {
+	d.clients = clients
+	d.removed = 0
+	d.reindex()
+
+	maxID := 0
+	for _, client := range d.clients {
+		if client.ID > maxID {
+			maxID = client.ID
+		}
+	}
+	d.lastID = maxID
+}
+
+// appendClients añade clientes al final del dataset y los indexa (requiere el
+// lock de escritura)
+func (d *dataset) appendClients(clients []*models.Client) {
+	for _, client := range clients {
+		d.index.add(client, len(d.clients))
+		d.search.add(client)
+		d.clients = append(d.clients, client)
+		d.lastID = max(d.lastID, client.ID)
+	}
+}
+
+// replaceContents reemplaza los clientes y los índices del dataset por los
+// de other, que no debe seguir usándose (requiere el lock de escritura)
+func (d *dataset) replaceContents(other *dataset) {
+	d.clients = other.clients
+	d.removed = other.removed
+	d.index = other.index
+	d.search = other.search
+	d.lastID = other.lastID
+}
+
+// reindex reconstruye los índices, por ejemplo tras revalidar clientes que
+// pudieron cambiar sus campos. Compacta antes los clientes (requiere el lock
+// de escritura).
+func (d *dataset) reindex() {
+	d.compact()
+	d.index = newClientIndex(d.clients)
+	d.search = newSearchIndex(d.clients)
+}
+
+// clientByID obtiene un cliente y su posición (requiere el lock de lectura)
+func (d *dataset) clientByID(id int) (*models.Client, int, bool) {
+	pos, exists := d.index.position[id]
+	if !exists {
+		return nil, 0, false
+	}
+	return d.clients[pos], pos, true
+}
+
+// replaceClient sustituye el cliente de la posición pos (requiere el lock de escritura)
+func (d *dataset) replaceClient(pos int, client *models.Client) {
+	d.index.remove(d.clients[pos])
+	d.search.remove(d.clients[pos])
+	d.clients[pos] = client
+	d.index.add(client, pos)
+	d.search.add(client)
+}
+
+// removeClient elimina el cliente de la posición pos dejando su posición
+// vacía, sin desplazar al resto. Cuando la mitad de las posiciones están
+// vacías se compactan, de modo que el costo por cliente eliminado es
+// constante en promedio (requiere el lock de escritura).
+func (d *dataset) removeClient(pos int) {
+	d.index.remove(d.clients[pos])
+	d.search.remove(d.clients[pos])
+
+	d.clients[pos] = nil
+	d.removed++
+
+	if d.removed >= minCompaction && d.removed*2 >= len(d.clients) {
+		d.compact()
+	}
+}
+
+// minCompaction posiciones vacías a partir de las cuales se compacta el dataset
+const minCompaction = 64
+
+// compact elimina las posiciones vacías de clients conservando el orden y
+// actualiza la posición de los clientes (requiere el lock de escritura)
+func (d *dataset) compact() {
+	if d.removed == 0 {
+		return
+	}
+
+	live := 0
+	for _, client := range d.clients {
+		if client == nil {
+			continue
+		}
+		d.clients[live] = client
+		d.index.position[client.ID] = live
+		live++
+	}
+	clear(d.clients[live:])
+	d.clients = d.clients[:live]
+	d.removed = 0
+}
+
+// removeClients conserva solo los clientes de kept, que deben estar en el
+// orden del dataset, y reconstruye los índices (requiere el lock de escritura)
+func (d *dataset) removeClients(kept []*models.Client) {
+	d.clients = kept
+	d.removed = 0
+	d.reindex()
+}
+
+// lookup busca los clientes cuyo campo indexado coincide con value, en el
+// orden del dataset (requiere el lock de lectura)
+func (d *dataset) lookup(field, value string) ([]*models.Client, error) {
+	var ids idSet
+	switch field {
+	case "clave":
+		ids = d.index.byClave[strings.TrimSpace(value)]
+	case "correo":
+		ids = d.index.byEmail[utils.NormalizeEmail(value)]
+	case "telefono":
+		ids = d.index.byPhone[utils.NormalizePhone(value)]
+	default:
+		return nil, errors.NewValidationError(field, "el campo no admite búsqueda exacta")
+	}
+
+	clients := make([]*models.Client, 0, len(ids))
+	for id := range ids {
+		client, _, _ := d.clientByID(id)
+		clients = append(clients, client)
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		return d.index.position[clients[i].ID] < d.index.position[clients[j].ID]
+	})
+
+	return clients, nil
+}
+
+// searchClients busca clientes por texto libre ordenados por relevancia y,
+// a igual relevancia, en el orden del dataset (requiere el lock de lectura)
+func (d *dataset) searchClients(query string) []*models.ClientSearchResult {
+	scores := d.search.search(query)
+
+	results := make([]*models.ClientSearchResult, 0, len(scores))
+	for id, score := range scores {
+		client, _, _ := d.clientByID(id)
+		results = append(results, &models.ClientSearchResult{Client: client, Score: score})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return d.index.position[results[i].ID] < d.index.position[results[j].ID]
+	})
+
+	return results
+}
+
+// ValidateDatasetName verifica que el nombre del dataset sea válido
+func ValidateDatasetName(name string) error {
+	if !datasetNameRegex.MatchString(name) {
+		return errors.ErrInvalidDatasetName
+	}
+	return nil
+}
+
+// datasetKey identifica un dataset dentro de su inquilino
+type datasetKey struct {
+	tenant string
+	name   string
+}
+
+// ListDatasets obtiene los datasets del inquilino de ctx ordenados por nombre
+func (s *clientService) ListDatasets(ctx context.Context) []*models.Dataset {
+	// El dataset por defecto siempre existe
+	if _, err := s.getOrCreateDataset(ctx, models.DefaultDatasetName); err != nil {
+		return nil
+	}
+
+	tenantID := tenant.FromContext(ctx)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	datasets := make([]*models.Dataset, 0)
+	for key, ds := range s.datasets {
+		if key.tenant == tenantID {
+			datasets = append(datasets, ds.info())
+		}
+	}
+
+	sort.Slice(datasets, func(i, j int) bool {
+		return datasets[i].Name < datasets[j].Name
+	})
+
+	return datasets
+}
+
+// GetDataset obtiene la información de un dataset
+func (s *clientService) GetDataset(ctx context.Context, name string) (*models.Dataset, error) {
+	ds, err := s.getDataset(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ds.info(), nil
+}
+
+// RenameDataset cambia el nombre de un dataset existente
+func (s *clientService) RenameDataset(ctx context.Context, name, newName string) (*models.Dataset, error) {
+	if err := ValidateDatasetName(newName); err != nil {
+		return nil, err
+	}
+
+	key := s.datasetKey(ctx, name)
+	newKey := s.datasetKey(ctx, newName)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ds, exists := s.datasets[key]
+	if !exists {
+		return nil, errors.ErrDatasetNotFound
+	}
+
+	if name == newName {
+		return ds.info(), nil
+	}
+
+	if _, exists := s.datasets[newKey]; exists {
+		return nil, errors.ErrDatasetAlreadyExists
+	}
+
+	ds.mu.Lock()
+	ds.name = newName
+	ds.touch()
+	ds.mu.Unlock()
+
+	delete(s.datasets, key)
+	s.datasets[newKey] = ds
+
+	return ds.info(), nil
+}
+
+// DeleteDataset elimina un dataset y todos sus clientes
+func (s *clientService) DeleteDataset(ctx context.Context, name string) error {
+	key := s.datasetKey(ctx, name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.datasets[key]; !exists {
+		return errors.ErrDatasetNotFound
+	}
+
+	delete(s.datasets, key)
+
+	return nil
+}
+
+// Métodos auxiliares privados
+
+// datasetKey obtiene la clave del dataset name en el inquilino de ctx
+func (s *clientService) datasetKey(ctx context.Context, name string) datasetKey {
+	return datasetKey{tenant: tenant.FromContext(ctx), name: name}
+}
+
+// getDataset obtiene un dataset existente del inquilino de ctx. El dataset
+// por defecto siempre existe.
+func (s *clientService) getDataset(ctx context.Context, name string) (*dataset, error) {
+	if name == "" || name == models.DefaultDatasetName {
+		return s.getOrCreateDataset(ctx, models.DefaultDatasetName)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ds, exists := s.datasets[s.datasetKey(ctx, name)]
+	if !exists {
+		return nil, errors.ErrDatasetNotFound
+	}
+
+	return ds, nil
+}
+
+// getOrCreateDataset obtiene un dataset del inquilino de ctx, creándolo si no existe
+func (s *clientService) getOrCreateDataset(ctx context.Context, name string) (*dataset, error) {
+	if name == "" {
+		name = models.DefaultDatasetName
+	}
+
+	if err := ValidateDatasetName(name); err != nil {
+		return nil, err
+	}
+
+	key := s.datasetKey(ctx, name)
+
+	s.mu.RLock()
+	ds, exists := s.datasets[key]
+	s.mu.RUnlock()
+	if exists {
+		return ds, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Otro goroutine pudo haberlo creado mientras esperábamos el lock
+	if ds, exists := s.datasets[key]; exists {
+		return ds, nil
+	}
+
+	ds = newDataset(name)
+	s.datasets[key] = ds
+
+	return ds, nil
+}
@@ -3,8 +3,10 @@ package services
 import (
 	"client-data-compiler/internal/domain/errors"
 	"client-data-compiler/internal/domain/models"
+	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -12,113 +14,175 @@ import (
 )
 
 type ExcelService interface {
-	ReadExcelFile(filePath string) ([]*models.Client, error)
-	WriteExcelFile(clients []*models.Client, filePath string) error
-	ValidateExcelStructure(filePath string) error
+	ReadExcelFile(ctx context.Context, filePath string, progress ProgressReporter) ([]*models.Client, error)
+	StreamExcelFile(ctx context.Context, filePath string, batchSize int, progress ProgressReporter, fn ClientBatchFunc) error
+	WriteExcel(ctx context.Context, clients []*models.Client, w io.Writer) error
+	ValidateExcelStructure(ctx context.Context, filePath string) error
 }
 
+// ClientBatchFunc recibe cada bloque de clientes leído por StreamExcelFile.
+// Si devuelve un error la lectura se detiene y el error se propaga.
+type ClientBatchFunc func(batch []*models.Client) error
+
 type excelService struct{}
 
+const (
+	// progressBatchSize cantidad de filas entre cada reporte de avance
+	progressBatchSize = 100
+	// streamBatchSize tamaño de bloque por defecto de StreamExcelFile
+	streamBatchSize = 1000
+)
+
 func NewExcelService() ExcelService {
 	return &excelService{}
 }
 
-// ReadExcelFile lee un archivo Excel y devuelve una lista de clientes
-func (s *excelService) ReadExcelFile(filePath string) ([]*models.Client, error) {
-	log.Printf("Iniciando lectura del archivo Excel: %s", filePath)
+// ReadExcelFile lee un archivo Excel y devuelve una lista de clientes,
+// reportando las filas leídas y deteniéndose si ctx se cancela
+func (s *excelService) ReadExcelFile(ctx context.Context, filePath string, progress ProgressReporter) ([]*models.Client, error) {
+	var clients []*models.Client
 
-	// Verificar extensión del archivo
-	if !strings.HasSuffix(strings.ToLower(filePath), ".xlsx") {
-		log.Printf("Extensión de archivo inválida: %s", filePath)
-		return nil, errors.ErrInvalidFileFormat
+	err := s.StreamExcelFile(ctx, filePath, streamBatchSize, progress, func(batch []*models.Client) error {
+		clients = append(clients, batch...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Abrir archivo Excel
-	f, err := excelize.OpenFile(filePath)
+	return clients, nil
+}
+
+// StreamExcelFile lee un archivo Excel fila a fila con el iterador de excelize
+// y entrega los clientes a fn en bloques de batchSize, de modo que nunca se
+// carga la hoja completa en memoria. Los encabezados se validan con la primera
+// fila antes de leer los datos.
+func (s *excelService) StreamExcelFile(ctx context.Context, filePath string, batchSize int, progress ProgressReporter, fn ClientBatchFunc) error {
+	slog.DebugContext(ctx, "Iniciando lectura del archivo Excel", "path", filePath)
+
+	progress = progressOrNoop(progress)
+	if batchSize <= 0 {
+		batchSize = streamBatchSize
+	}
+
+	f, rows, err := s.openRows(ctx, filePath)
 	if err != nil {
-		log.Printf("Error abriendo archivo Excel %s: %v", filePath, err)
-		return nil, errors.NewFileProcessingError(fmt.Sprintf("Error abriendo archivo: %v", err))
+		return err
 	}
 	defer f.Close()
+	defer rows.Close()
 
-	log.Printf("Archivo Excel abierto exitosamente")
+	// Validar estructura del encabezado
+	if !rows.Next() {
+		slog.DebugContext(ctx, "Archivo sin contenido")
+		return errors.ErrFileEmpty
+	}
+	headers, err := rows.Columns()
+	if err != nil {
+		return errors.NewFileProcessingError(fmt.Sprintf("Error leyendo filas: %v", err))
+	}
 
-	// Obtener la primera hoja
-	sheetName := f.GetSheetName(0)
-	if sheetName == "" {
-		log.Printf("No se encontraron hojas en el archivo")
-		return nil, errors.NewFileProcessingError("No se encontraron hojas en el archivo")
+	if err := s.validateHeaders(ctx, headers); err != nil {
+		slog.DebugContext(ctx, "Encabezados inválidos", "headers", headers, "error", err)
+		return err
 	}
 
-	log.Printf("Procesando hoja: %s", sheetName)
+	progress.Stage(models.StageHeadersValidated)
+	progress.Stage(models.StageReading)
 
-	// Obtener todas las filas
-	rows, err := f.GetRows(sheetName)
-	if err != nil {
-		log.Printf("Error leyendo filas de la hoja %s: %v", sheetName, err)
-		return nil, errors.NewFileProcessingError(fmt.Sprintf("Error leyendo filas: %v", err))
+	// Procesar datos
+	batch := make([]*models.Client, 0, batchSize)
+	total := 0
+	rowNumber := 1
+	pendingRows := 0
+	// Las filas vacías solo se convierten en clientes si después aparece una
+	// fila con datos, igual que GetRows descarta las filas vacías finales
+	emptyRows := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		batch = make([]*models.Client, 0, batchSize)
+		return nil
 	}
 
-	log.Printf("Total de filas encontradas: %d", len(rows))
+	addClient := func(row []string) error {
+		// Reportar avance y verificar cancelación por bloques de filas
+		if pendingRows == progressBatchSize {
+			progress.RowsRead(pendingRows)
+			pendingRows = 0
+			if ctx.Err() != nil {
+				return errors.NewContextError(ctx.Err())
+			}
+		}
+		pendingRows++
+		total++
 
-	if len(rows) < 1 {
-		log.Printf("Archivo sin contenido")
-		return nil, errors.ErrFileEmpty
-	}
+		client := newClientFromRow(row, total, total+1)
 
-	if len(rows) < 2 {
-		log.Printf("Archivo solo tiene encabezados, sin datos")
-		return nil, errors.NewFileProcessingError("El archivo solo contiene encabezados, sin datos")
-	}
+		// Una entrada por fila: solo en debug, y sin datos personales completos
+		slog.DebugContext(ctx, "Fila leída", "row", client.RowNumber, "clave", client.Clave,
+			"correo", client.Correo, "telefono", client.Telefono)
 
-	// Validar estructura del encabezado
-	log.Printf("Validando encabezados: %v", rows[0])
-	if err := s.validateHeaders(rows[0]); err != nil {
-		log.Printf("Error en validación de encabezados: %v", err)
-		return nil, err
+		batch = append(batch, client)
+		if len(batch) == batchSize {
+			return flush()
+		}
+		return nil
 	}
 
-	log.Printf("Encabezados validados correctamente")
+	for rows.Next() {
+		rowNumber++
 
-	// Procesar datos
-	var clients []*models.Client
-	for i, row := range rows[1:] { // Saltar encabezado
-		rowNumber := i + 2 // +2 porque empezamos en fila 1 y saltamos encabezado
+		row, err := rows.Columns()
+		if err != nil {
+			slog.WarnContext(ctx, "Error leyendo una fila", "row", rowNumber, "error", err)
+			return errors.NewFileProcessingError(fmt.Sprintf("Error leyendo filas: %v", err))
+		}
 
-		log.Printf("Procesando fila %d: %v", rowNumber, row)
+		if len(row) == 0 {
+			emptyRows++
+			continue
+		}
 
-		// Asegurar que la fila tenga al menos 4 columnas
-		for len(row) < 4 {
-			row = append(row, "")
+		for ; emptyRows > 0; emptyRows-- {
+			if err := addClient(nil); err != nil {
+				return err
+			}
 		}
 
-		client := &models.Client{
-			ID:        i + 1,
-			Clave:     strings.TrimSpace(row[0]),
-			Nombre:    strings.TrimSpace(row[1]),
-			Correo:    strings.TrimSpace(row[2]),
-			Telefono:  strings.TrimSpace(row[3]),
-			RowNumber: rowNumber,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			Errors:    make(map[string]string),
-			IsValid:   true,
+		if err := addClient(row); err != nil {
+			return err
 		}
+	}
+
+	if err := rows.Error(); err != nil {
+		return errors.NewFileProcessingError(fmt.Sprintf("Error leyendo filas: %v", err))
+	}
 
-		// Log del cliente creado
-		log.Printf("Cliente creado: ID=%d, Clave=%s, Nombre=%s, Correo=%s, Telefono=%s",
-			client.ID, client.Clave, client.Nombre, client.Correo, client.Telefono)
+	if total == 0 {
+		slog.DebugContext(ctx, "Archivo solo tiene encabezados, sin datos")
+		return errors.NewFileProcessingError("El archivo solo contiene encabezados, sin datos")
+	}
 
-		clients = append(clients, client)
+	if err := flush(); err != nil {
+		return err
 	}
 
-	log.Printf("Procesamiento completado: %d clientes creados", len(clients))
-	return clients, nil
+	progress.RowsRead(pendingRows)
+
+	slog.InfoContext(ctx, "Lectura del archivo Excel completada", "rows", total)
+	return nil
 }
 
-// WriteExcelFile escribe una lista de clientes a un archivo Excel
-func (s *excelService) WriteExcelFile(clients []*models.Client, filePath string) error {
-	log.Printf("Iniciando escritura de archivo Excel: %s con %d clientes", filePath, len(clients))
+// WriteExcel escribe una lista de clientes como libro de Excel en w. Si ctx
+// termina antes de guardar, no se escribe nada.
+func (s *excelService) WriteExcel(ctx context.Context, clients []*models.Client, w io.Writer) error {
+	slog.DebugContext(ctx, "Iniciando escritura del archivo Excel", "clients", len(clients))
 
 	f := excelize.NewFile()
 	defer f.Close()
@@ -127,7 +191,7 @@ func (s *excelService) WriteExcelFile(clients []*models.Client, filePath string)
 	sheetName := "Clientes"
 	index, err := f.NewSheet(sheetName)
 	if err != nil {
-		log.Printf("Error creando hoja %s: %v", sheetName, err)
+		slog.ErrorContext(ctx, "Error creando la hoja", "sheet", sheetName, "error", err)
 		return errors.NewFileProcessingError(fmt.Sprintf("Error creando hoja: %v", err))
 	}
 
@@ -156,6 +220,10 @@ func (s *excelService) WriteExcelFile(clients []*models.Client, filePath string)
 
 	// Escribir datos
 	for i, client := range clients {
+		if i%progressBatchSize == 0 && ctx.Err() != nil {
+			return errors.NewContextError(ctx.Err())
+		}
+
 		row := i + 2 // +2 porque empezamos en fila 2 (después del encabezado)
 
 		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), client.Clave)
@@ -195,18 +263,22 @@ func (s *excelService) WriteExcelFile(clients []*models.Client, filePath string)
 		s.createErrorSheet(f, clients)
 	}
 
+	if ctx.Err() != nil {
+		return errors.NewContextError(ctx.Err())
+	}
+
 	// Guardar archivo
-	if err := f.SaveAs(filePath); err != nil {
-		log.Printf("Error guardando archivo %s: %v", filePath, err)
+	if err := f.Write(w); err != nil {
+		slog.ErrorContext(ctx, "Error guardando el archivo Excel", "error", err)
 		return errors.NewFileProcessingError(fmt.Sprintf("Error guardando archivo: %v", err))
 	}
 
-	log.Printf("Archivo Excel guardado exitosamente: %s", filePath)
+	slog.DebugContext(ctx, "Archivo Excel escrito", "clients", len(clients))
 	return nil
 }
 
 // validateHeaders valida que los encabezados sean correctos
-func (s *excelService) validateHeaders(headers []string) error {
+func (s *excelService) validateHeaders(ctx context.Context, headers []string) error {
 	expectedHeaders := []string{"clave", "nombre", "correo", "telefono"}
 
 	if len(headers) < 4 {
@@ -222,8 +294,8 @@ func (s *excelService) validateHeaders(headers []string) error {
 		normalized = strings.ReplaceAll(normalized, "é", "e")
 		normalized = strings.ReplaceAll(normalized, "teléfono", "telefono")
 
-		log.Printf("Comparando encabezado %d: '%s' (normalizado: '%s') con esperado: '%s'",
-			i+1, header, normalized, expectedHeaders[i])
+		slog.DebugContext(ctx, "Comparando encabezado", "column", i+1, "header", header,
+			"normalized", normalized, "expected", expectedHeaders[i])
 
 		if normalized != expectedHeaders[i] {
 			return errors.NewFileProcessingError(
@@ -236,41 +308,85 @@ func (s *excelService) validateHeaders(headers []string) error {
 	return nil
 }
 
-// ValidateExcelStructure valida que el archivo Excel tenga la estructura correcta
-func (s *excelService) ValidateExcelStructure(filePath string) error {
-	log.Printf("Validando estructura del archivo Excel: %s", filePath)
+// ValidateExcelStructure valida que el archivo Excel tenga la estructura
+// correcta. Solo se lee la primera fila de la hoja.
+func (s *excelService) ValidateExcelStructure(ctx context.Context, filePath string) error {
+	slog.DebugContext(ctx, "Validando estructura del archivo Excel", "path", filePath)
+
+	f, rows, err := s.openRows(ctx, filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	defer rows.Close()
+
+	if !rows.Next() {
+		return errors.ErrFileEmpty
+	}
+
+	headers, err := rows.Columns()
+	if err != nil {
+		return errors.NewFileProcessingError(fmt.Sprintf("Error leyendo archivo: %v", err))
+	}
 
-	// Verificar extensión
+	// Validar encabezados
+	return s.validateHeaders(ctx, headers)
+}
+
+// openRows abre un archivo Excel y devuelve un iterador sobre las filas de su
+// primera hoja. El llamador debe cerrar ambos.
+func (s *excelService) openRows(ctx context.Context, filePath string) (*excelize.File, *excelize.Rows, error) {
+	// Verificar extensión del archivo
 	if !strings.HasSuffix(strings.ToLower(filePath), ".xlsx") {
-		return errors.ErrInvalidFileFormat
+		slog.DebugContext(ctx, "Extensión de archivo inválida", "path", filePath)
+		return nil, nil, errors.ErrInvalidFileFormat
 	}
 
-	// Abrir archivo
+	// Abrir archivo Excel
 	f, err := excelize.OpenFile(filePath)
 	if err != nil {
-		log.Printf("Error abriendo archivo para validación: %v", err)
-		return errors.NewFileProcessingError(fmt.Sprintf("Error abriendo archivo: %v", err))
+		slog.WarnContext(ctx, "Error abriendo el archivo Excel", "path", filePath, "error", err)
+		return nil, nil, errors.NewFileProcessingError(fmt.Sprintf("Error abriendo archivo: %v", err))
 	}
-	defer f.Close()
 
-	// Verificar que tenga al menos una hoja
+	// Obtener la primera hoja
 	sheetName := f.GetSheetName(0)
 	if sheetName == "" {
-		return errors.ErrInvalidExcelStructure
+		f.Close()
+		slog.DebugContext(ctx, "No se encontraron hojas en el archivo", "path", filePath)
+		return nil, nil, errors.ErrInvalidExcelStructure
 	}
 
-	// Obtener filas
-	rows, err := f.GetRows(sheetName)
+	rows, err := f.Rows(sheetName)
 	if err != nil {
-		return errors.NewFileProcessingError(fmt.Sprintf("Error leyendo archivo: %v", err))
+		f.Close()
+		slog.WarnContext(ctx, "Error leyendo las filas de la hoja", "sheet", sheetName, "error", err)
+		return nil, nil, errors.NewFileProcessingError(fmt.Sprintf("Error leyendo filas: %v", err))
 	}
 
-	if len(rows) < 1 {
-		return errors.ErrFileEmpty
+	return f, rows, nil
+}
+
+// newClientFromRow crea un cliente a partir de las columnas de una fila
+func newClientFromRow(row []string, id, rowNumber int) *models.Client {
+	// Asegurar que la fila tenga al menos 4 columnas
+	for len(row) < 4 {
+		row = append(row, "")
 	}
 
-	// Validar encabezados
-	return s.validateHeaders(rows[0])
+	now := time.Now()
+	return &models.Client{
+		ID:        id,
+		Clave:     strings.TrimSpace(row[0]),
+		Nombre:    strings.TrimSpace(row[1]),
+		Correo:    strings.TrimSpace(row[2]),
+		Telefono:  strings.TrimSpace(row[3]),
+		RowNumber: rowNumber,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Errors:    make(map[string]string),
+		IsValid:   true,
+	}
 }
 
 // createErrorSheet crea una hoja con el detalle de errores
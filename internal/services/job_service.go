@@ -0,0 +1,476 @@
+package services
+
+import (
+	"client-data-compiler/internal/domain/errors"
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/logging"
+	"client-data-compiler/internal/tenant"
+	"client-data-compiler/internal/utils"
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JobFunc función que ejecuta un trabajo en segundo plano. Debe respetar la
+// cancelación del contexto y reportar su avance en progress.
+type JobFunc func(ctx context.Context, progress ProgressReporter) (interface{}, error)
+
+type JobService interface {
+	Start(ctx context.Context, jobType string, metadata map[string]string, timeout time.Duration, fn JobFunc) *models.Job
+	Get(ctx context.Context, id string) (*models.Job, error)
+	List(ctx context.Context) []*models.Job
+	Cancel(ctx context.Context, id string) (*models.Job, error)
+	Wait(ctx context.Context, id string) (*models.Job, error)
+	Subscribe(ctx context.Context, id string, afterEventID int) ([]models.JobEvent, <-chan models.JobEvent, func(), error)
+	Shutdown(ctx context.Context) error
+}
+
+const (
+	// maxJobEvents eventos que se conservan por trabajo para reenviarlos a
+	// suscriptores que se conectan tarde
+	maxJobEvents = 500
+	// eventThrottle intervalo mínimo entre eventos de conteo de filas
+	eventThrottle = 250 * time.Millisecond
+	// subscriberBuffer tamaño del buffer de cada suscriptor; si se llena, los
+	// eventos intermedios se descartan para ese suscriptor
+	subscriberBuffer = 64
+	// jobCancelGrace tiempo que Shutdown espera a que los trabajos cancelados
+	// registren su cancelación
+	jobCancelGrace = 10 * time.Second
+)
+
+type jobService struct {
+	jobs      map[string]*job
+	mu        sync.RWMutex
+	retention time.Duration
+
+	// running trabajos en ejecución, que Shutdown espera
+	running sync.WaitGroup
+	// closing indica que el servicio se está apagando y no acepta trabajos
+	closing bool
+}
+
+// NewJobService crea el servicio de trabajos. Los trabajos terminados se
+// conservan durante retention y después se descartan.
+func NewJobService(retention time.Duration) JobService {
+	return &jobService{
+		jobs:      make(map[string]*job),
+		retention: retention,
+	}
+}
+
+// Start registra un trabajo del inquilino de ctx y lo ejecuta en una
+// goroutine. El trabajo no depende de la cancelación de ctx, pero se ejecuta
+// con su inquilino. Si timeout es mayor que cero, el trabajo se cancela al
+// vencer ese plazo y termina como fallido con OPERATION_TIMEOUT. Durante el
+// apagado el trabajo no se ejecuta y termina como fallido con SHUTTING_DOWN.
+func (s *jobService) Start(ctx context.Context, jobType string, metadata map[string]string, timeout time.Duration, fn JobFunc) *models.Job {
+	s.pruneExpired()
+
+	id := utils.GenerateID()
+	tenantID := tenant.FromContext(ctx)
+	parent := tenant.WithTenant(context.Background(), tenantID)
+	parent = logging.With(logging.Inherit(parent, ctx), "job_id", id)
+
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+
+	j := &job{
+		data: models.Job{
+			ID:        id,
+			Type:      jobType,
+			Status:    models.JobStatusPending,
+			Metadata:  metadata,
+			Progress:  models.JobProgress{Stage: models.StageQueued},
+			CreatedAt: time.Now(),
+		},
+		tenant:        tenantID,
+		cancel:        cancel,
+		done:          make(chan struct{}),
+		subscribers:   make(map[chan models.JobEvent]struct{}),
+		lastPublished: make(map[string]time.Time),
+	}
+
+	j.mu.Lock()
+	j.publish(models.StageQueued, "")
+	j.mu.Unlock()
+
+	s.mu.Lock()
+	s.jobs[j.data.ID] = j
+	closing := s.closing
+	if !closing {
+		s.running.Add(1)
+	}
+	s.mu.Unlock()
+
+	if closing {
+		cancel()
+		j.finish(nil, errors.ErrShuttingDown, nil, s.retention)
+		close(j.done)
+		return j.snapshot()
+	}
+
+	go s.run(ctx, j, fn)
+
+	return j.snapshot()
+}
+
+// Get obtiene el estado actual de un trabajo
+func (s *jobService) Get(ctx context.Context, id string) (*models.Job, error) {
+	j, err := s.getJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return j.snapshot(), nil
+}
+
+// List obtiene los trabajos del inquilino de ctx, del más reciente al más antiguo
+func (s *jobService) List(ctx context.Context) []*models.Job {
+	s.pruneExpired()
+
+	tenantID := tenant.FromContext(ctx)
+
+	s.mu.RLock()
+	jobs := make([]*models.Job, 0)
+	for _, j := range s.jobs {
+		if j.tenant == tenantID {
+			jobs = append(jobs, j.snapshot())
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+
+	return jobs
+}
+
+// Cancel solicita la cancelación de un trabajo pendiente o en ejecución
+func (s *jobService) Cancel(ctx context.Context, id string) (*models.Job, error) {
+	j, err := s.getJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := j.snapshot()
+	if snapshot.IsFinished() {
+		return snapshot, errors.ErrJobFinished
+	}
+
+	j.cancel()
+
+	return j.snapshot(), nil
+}
+
+// Wait espera a que un trabajo termine o a que se cancele ctx
+func (s *jobService) Wait(ctx context.Context, id string) (*models.Job, error) {
+	j, err := s.getJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-j.done:
+		return j.snapshot(), nil
+	case <-ctx.Done():
+		return j.snapshot(), ctx.Err()
+	}
+}
+
+// Subscribe se suscribe a los eventos de un trabajo. Devuelve los eventos ya
+// emitidos con ID mayor que afterEventID y un canal con los siguientes, que se
+// cierra cuando el trabajo termina. La función devuelta cancela la suscripción.
+func (s *jobService) Subscribe(ctx context.Context, id string, afterEventID int) ([]models.JobEvent, <-chan models.JobEvent, func(), error) {
+	j, err := s.getJob(ctx, id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	history, ch, unsubscribe := j.subscribe(afterEventID)
+	return history, ch, unsubscribe, nil
+}
+
+// Shutdown deja de aceptar trabajos y espera a que terminen los que están en
+// curso. Si ctx termina antes, los cancela, espera como máximo jobCancelGrace
+// a que registren su cancelación (y eliminen sus archivos parciales) y
+// devuelve el error de ctx.
+func (s *jobService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mu.RLock()
+	cancelled := 0
+	for _, j := range s.jobs {
+		if !j.snapshot().IsFinished() {
+			j.cancel()
+			cancelled++
+		}
+	}
+	s.mu.RUnlock()
+
+	// Los trabajos terminaron a la vez que ctx
+	if cancelled == 0 {
+		<-done
+		return nil
+	}
+	slog.Warn("Apagado: se cancelan los trabajos en curso", "jobs", cancelled)
+
+	select {
+	case <-done:
+	case <-time.After(jobCancelGrace):
+		slog.Error("Apagado: hay trabajos que no terminaron después de cancelarlos", "grace", jobCancelGrace)
+	}
+
+	return ctx.Err()
+}
+
+// Métodos auxiliares privados
+
+// run ejecuta el trabajo y registra su resultado
+func (s *jobService) run(ctx context.Context, j *job, fn JobFunc) {
+	defer s.running.Done()
+	defer close(j.done)
+	defer j.cancel()
+
+	j.start()
+
+	result, err := func() (result interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("error inesperado: %v", r)
+			}
+		}()
+		return fn(ctx, j)
+	}()
+
+	j.finish(result, err, ctx.Err(), s.retention)
+
+	snapshot := j.snapshot()
+	attrs := []any{"type", snapshot.Type, "status", snapshot.Status}
+	if snapshot.Error != "" {
+		attrs = append(attrs, "error", snapshot.Error)
+	}
+	slog.InfoContext(ctx, "Trabajo terminado", attrs...)
+}
+
+// getJob obtiene un trabajo registrado del inquilino de ctx; los de otros
+// inquilinos se tratan como inexistentes
+func (s *jobService) getJob(ctx context.Context, id string) (*job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	j, exists := s.jobs[id]
+	if !exists || j.tenant != tenant.FromContext(ctx) {
+		return nil, errors.ErrJobNotFound
+	}
+
+	return j, nil
+}
+
+// pruneExpired descarta los trabajos terminados cuyo periodo de retención venció
+func (s *jobService) pruneExpired() {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, j := range s.jobs {
+		snapshot := j.snapshot()
+		if snapshot.ExpiresAt != nil && now.After(*snapshot.ExpiresAt) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// job estado interno de un trabajo; implementa ProgressReporter
+type job struct {
+	data   models.Job
+	tenant string
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	events        []models.JobEvent
+	lastEventID   int
+	subscribers   map[chan models.JobEvent]struct{}
+	lastPublished map[string]time.Time
+}
+
+// snapshot devuelve una copia del estado del trabajo
+func (j *job) snapshot() *models.Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	snapshot := j.data
+	return &snapshot
+}
+
+func (j *job) start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	j.data.Status = models.JobStatusRunning
+	j.data.StartedAt = &now
+}
+
+// finish registra el resultado del trabajo. ctxErr es el error del contexto
+// del trabajo, si se canceló o venció su plazo.
+func (j *job) finish(result interface{}, err error, ctxErr error, retention time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	expiresAt := now.Add(retention)
+	j.data.FinishedAt = &now
+	j.data.ExpiresAt = &expiresAt
+	j.data.Result = result
+
+	switch {
+	case ctxErr == context.Canceled:
+		j.data.Status = models.JobStatusCancelled
+		j.data.Error = errors.ErrOperationCancelled.Message
+		j.publish(models.EventCancelled, j.data.Error)
+	case ctxErr == context.DeadlineExceeded:
+		j.data.Status = models.JobStatusFailed
+		j.data.Error = errors.ErrOperationTimeout.Message
+		j.publish(models.EventFailed, j.data.Error)
+	case err != nil:
+		j.data.Status = models.JobStatusFailed
+		j.data.Error = err.Error()
+		j.publish(models.EventFailed, j.data.Error)
+	default:
+		j.data.Status = models.JobStatusCompleted
+		j.data.Progress.Stage = models.StageDone
+		j.publish(models.EventDone, "")
+	}
+
+	// No habrá más eventos: cerrar los canales de los suscriptores
+	for ch := range j.subscribers {
+		close(ch)
+		delete(j.subscribers, ch)
+	}
+}
+
+// Stage implementa ProgressReporter
+func (j *job) Stage(stage string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.data.Progress.Stage = stage
+	j.publish(stage, "")
+}
+
+// RowsRead implementa ProgressReporter
+func (j *job) RowsRead(n int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.data.Progress.RowsRead += n
+	j.publishThrottled(models.EventRowsParsed)
+}
+
+// RowsValidated implementa ProgressReporter
+func (j *job) RowsValidated(n, invalid int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.data.Progress.RowsValidated += n
+	j.data.Progress.ErrorsFound += invalid
+	j.publishThrottled(models.EventRowsValidated)
+}
+
+// subscribe registra un suscriptor y devuelve el historial pendiente de enviar
+func (j *job) subscribe(afterEventID int) ([]models.JobEvent, <-chan models.JobEvent, func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var history []models.JobEvent
+	for _, event := range j.events {
+		if event.ID > afterEventID {
+			history = append(history, event)
+		}
+	}
+
+	ch := make(chan models.JobEvent, subscriberBuffer)
+
+	// Si el trabajo ya terminó no habrá más eventos
+	if j.data.IsFinished() {
+		close(ch)
+		return history, ch, func() {}
+	}
+
+	j.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+
+		if _, exists := j.subscribers[ch]; exists {
+			delete(j.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return history, ch, unsubscribe
+}
+
+// publishThrottled publica un evento de conteo como máximo una vez por
+// eventThrottle (requiere el lock). Los conteos finales viajan en el
+// progreso del siguiente evento de etapa.
+func (j *job) publishThrottled(eventType string) {
+	now := time.Now()
+	if now.Sub(j.lastPublished[eventType]) < eventThrottle {
+		return
+	}
+	j.lastPublished[eventType] = now
+	j.publish(eventType, "")
+}
+
+// publish registra un evento y lo envía a los suscriptores (requiere el lock)
+func (j *job) publish(eventType, message string) {
+	j.lastEventID++
+	event := models.JobEvent{
+		ID:       j.lastEventID,
+		JobID:    j.data.ID,
+		Type:     eventType,
+		Progress: j.data.Progress,
+		Message:  message,
+		Time:     time.Now(),
+	}
+
+	j.events = append(j.events, event)
+	if len(j.events) > maxJobEvents {
+		j.events = j.events[len(j.events)-maxJobEvents:]
+	}
+
+	for ch := range j.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Suscriptor lento: se descarta el evento para no bloquear el trabajo
+		}
+	}
+}
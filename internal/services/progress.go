@@ -0,0 +1,33 @@
+package services
+
+// ProgressReporter recibe el avance de operaciones de larga duración
+// (importaciones y validaciones). Las implementaciones deben ser seguras
+// para uso concurrente.
+type ProgressReporter interface {
+	// Stage indica que la operación entró en una nueva etapa
+	Stage(stage string)
+	// RowsRead suma n filas leídas del archivo
+	RowsRead(n int)
+	// RowsValidated suma n filas validadas, de las cuales invalid tienen errores
+	RowsValidated(n, invalid int)
+}
+
+// noopProgress descarta el avance reportado
+type noopProgress struct{}
+
+func (noopProgress) Stage(string)           {}
+func (noopProgress) RowsRead(int)           {}
+func (noopProgress) RowsValidated(int, int) {}
+
+// NoopProgress devuelve un ProgressReporter que no hace nada
+func NoopProgress() ProgressReporter {
+	return noopProgress{}
+}
+
+// progressOrNoop evita tener que comprobar nil en cada llamada
+func progressOrNoop(progress ProgressReporter) ProgressReporter {
+	if progress == nil {
+		return noopProgress{}
+	}
+	return progress
+}
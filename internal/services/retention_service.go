@@ -0,0 +1,285 @@
+package services
+
+import (
+	"client-data-compiler/internal/domain/errors"
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/repository"
+	"client-data-compiler/internal/storage"
+	"client-data-compiler/internal/tenant"
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+)
+
+type RetentionService interface {
+	Report(ctx context.Context) (*models.RetentionReport, error)
+	Enforce(ctx context.Context) (*models.RetentionReport, error)
+	Start(ctx context.Context, interval time.Duration)
+}
+
+// RetentionPolicies tiempo que se conserva cada tipo de archivo desde su
+// último uso. Un plazo no positivo conserva los archivos indefinidamente.
+type RetentionPolicies struct {
+	Uploads   time.Duration
+	Exports   time.Duration
+	Templates time.Duration
+}
+
+// For obtiene el plazo de retención de un tipo de archivo
+func (p RetentionPolicies) For(kind models.FileKind) time.Duration {
+	switch kind {
+	case models.FileKindUpload:
+		return p.Uploads
+	case models.FileKindExport:
+		return p.Exports
+	case models.FileKindTemplate:
+		return p.Templates
+	default:
+		return 0
+	}
+}
+
+type retentionService struct {
+	store      storage.Storage
+	uploadRepo repository.UploadRepository
+	policies   RetentionPolicies
+}
+
+func NewRetentionService(store storage.Storage, uploadRepo repository.UploadRepository, policies RetentionPolicies) RetentionService {
+	return &retentionService{
+		store:      store,
+		uploadRepo: uploadRepo,
+		policies:   policies,
+	}
+}
+
+// Report obtiene, sin eliminarlos, los archivos vencidos del inquilino de ctx
+func (s *retentionService) Report(ctx context.Context) (*models.RetentionReport, error) {
+	return s.apply(ctx, tenant.FromContext(ctx), true)
+}
+
+// Enforce elimina los archivos vencidos de todos los inquilinos
+func (s *retentionService) Enforce(ctx context.Context) (*models.RetentionReport, error) {
+	report, err := s.apply(ctx, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	if report.TotalFiles == 0 {
+		return report, nil
+	}
+
+	removed := 0
+	var freed int64
+	for _, file := range report.Files {
+		if file.Removed {
+			removed++
+			freed += file.Size
+		}
+	}
+	slog.InfoContext(ctx, "Retención aplicada", "expired", report.TotalFiles, "removed", removed, "freed_bytes", freed)
+
+	return report, nil
+}
+
+// Start aplica la retención al iniciar y después cada interval, hasta que ctx
+// termine. Con interval no positivo no hace nada.
+func (s *retentionService) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Enforce(ctx); err != nil {
+				slog.ErrorContext(ctx, "Error aplicando la retención de archivos", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// uploadUsage uso de un archivo subido por los uploads registrados
+type uploadUsage struct {
+	lastUploadedAt time.Time
+	// processing indica que algún upload que lo utiliza se está procesando
+	processing bool
+	// uploads uploads registrados que utilizan el archivo
+	uploads []*models.Upload
+}
+
+// apply busca los archivos vencidos, solo del inquilino onlyTenant si no está
+// vacío, y los elimina salvo en un simulacro
+func (s *retentionService) apply(ctx context.Context, onlyTenant string, dryRun bool) (*models.RetentionReport, error) {
+	now := time.Now()
+	report := &models.RetentionReport{
+		DryRun: dryRun,
+		RunAt:  now,
+		Policies: map[models.FileKind]string{
+			models.FileKindUpload:   s.policies.Uploads.String(),
+			models.FileKindExport:   s.policies.Exports.String(),
+			models.FileKindTemplate: s.policies.Templates.String(),
+		},
+		Files: []*models.ExpiredFile{},
+	}
+
+	var objects []storage.ObjectInfo
+	for _, prefix := range []string{uploadsPrefix, templatesPrefix} {
+		listed, err := s.store.List(ctx, prefix)
+		if err != nil {
+			return nil, errors.NewFileProcessingError(fmt.Sprintf("Error leyendo archivos: %v", err))
+		}
+		objects = append(objects, listed...)
+	}
+
+	// Uso de los archivos subidos, por inquilino
+	usages := make(map[string]map[string]uploadUsage)
+
+	for _, object := range objects {
+		kind, tenantID, ok := classifyStorageKey(object.Key)
+		if !ok || (onlyTenant != "" && tenantID != onlyTenant) {
+			continue
+		}
+
+		retention := s.policies.For(kind)
+		if retention <= 0 {
+			continue
+		}
+
+		lastUsed := object.ModTime
+		var usage uploadUsage
+		if kind == models.FileKindUpload {
+			if usages[tenantID] == nil {
+				usage, err := s.uploadUsages(ctx, tenantID)
+				if err != nil {
+					return nil, err
+				}
+				usages[tenantID] = usage
+			}
+
+			usage = usages[tenantID][path.Base(object.Key)]
+			if usage.processing {
+				continue
+			}
+			if usage.lastUploadedAt.After(lastUsed) {
+				lastUsed = usage.lastUploadedAt
+			}
+		}
+
+		if now.Sub(lastUsed) < retention {
+			continue
+		}
+
+		file := &models.ExpiredFile{
+			Key:        object.Key,
+			Kind:       kind,
+			Tenant:     tenantID,
+			Size:       object.Size,
+			LastUsedAt: lastUsed,
+		}
+
+		if !dryRun {
+			if err := s.store.Delete(ctx, object.Key); err != nil {
+				file.Error = err.Error()
+				slog.ErrorContext(ctx, "Retención: error eliminando un archivo", "key", object.Key, "error", err)
+			} else {
+				file.Removed = true
+				slog.InfoContext(ctx, "Retención: archivo eliminado", "key", object.Key, "kind", kind,
+					"tenant", tenantID, "size", object.Size, "last_used_at", lastUsed)
+
+				if err := s.markExpired(ctx, tenantID, usage.uploads); err != nil {
+					file.Error = err.Error()
+					slog.ErrorContext(ctx, "Retención: error marcando uploads como vencidos", "key", object.Key, "error", err)
+				}
+			}
+		}
+
+		report.Files = append(report.Files, file)
+		report.TotalFiles++
+		report.TotalBytes += object.Size
+	}
+
+	return report, nil
+}
+
+// uploadUsages obtiene el uso de cada archivo subido del inquilino por su
+// nombre almacenado. Un archivo puede ser de varios uploads si se reimportó.
+func (s *retentionService) uploadUsages(ctx context.Context, tenantID string) (map[string]uploadUsage, error) {
+	uploads, err := s.uploadRepo.GetAll(tenant.WithTenant(ctx, tenantID))
+	if err != nil {
+		return nil, err
+	}
+
+	usages := make(map[string]uploadUsage, len(uploads))
+	for _, upload := range uploads {
+		usage := usages[upload.StoredName]
+		if upload.UploadedAt.After(usage.lastUploadedAt) {
+			usage.lastUploadedAt = upload.UploadedAt
+		}
+		if upload.Status == models.UploadStatusProcessing {
+			usage.processing = true
+		}
+		usage.uploads = append(usage.uploads, upload)
+		usages[upload.StoredName] = usage
+	}
+
+	return usages, nil
+}
+
+// markExpired marca como vencidos los uploads del inquilino cuyo archivo se
+// eliminó, para que su registro no apunte a un archivo inexistente
+func (s *retentionService) markExpired(ctx context.Context, tenantID string, uploads []*models.Upload) error {
+	ctx = tenant.WithTenant(ctx, tenantID)
+
+	for _, upload := range uploads {
+		if upload.Status == models.UploadStatusExpired {
+			continue
+		}
+
+		upload.Status = models.UploadStatusExpired
+		if _, err := s.uploadRepo.Update(ctx, upload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// classifyStorageKey obtiene el tipo de archivo y el inquilino de una clave de
+// almacenamiento, p. ej. uploads/tenants/<inquilino>/exports/<id>.xlsx. Las
+// claves que no siguen la estructura conocida no se clasifican.
+//
+// Como plantilla solo se clasifica la común, que se vuelve a generar si no
+// existe; las plantillas propias de cada inquilino las sube un administrador
+// y no pueden regenerarse, así que no vencen.
+func classifyStorageKey(key string) (models.FileKind, string, bool) {
+	parts := strings.Split(key, "/")
+	base, rest := parts[0], parts[1:]
+
+	tenantID := tenant.Default
+	if len(rest) >= 3 && rest[0] == "tenants" {
+		tenantID, rest = rest[1], rest[2:]
+	}
+
+	switch {
+	case base == uploadsPrefix && len(rest) == 1:
+		return models.FileKindUpload, tenantID, true
+	case base == uploadsPrefix && len(rest) == 2 && rest[0] == "exports":
+		return models.FileKindExport, tenantID, true
+	case base == templatesPrefix && tenantID == tenant.Default && len(rest) == 1 && rest[0] == templateName:
+		return models.FileKindTemplate, tenantID, true
+	default:
+		return "", "", false
+	}
+}
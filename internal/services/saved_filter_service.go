@@ -0,0 +1,115 @@
+package services
+
+import (
+	"client-data-compiler/internal/domain/errors"
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/query"
+	"client-data-compiler/internal/repository"
+	"context"
+	"strings"
+	"time"
+)
+
+// SavedFilterService gestiona los filtros guardados con nombre
+type SavedFilterService interface {
+	CreateFilter(ctx context.Context, filter *models.SavedFilter) (*models.SavedFilter, error)
+	UpdateFilter(ctx context.Context, name string, filter *models.SavedFilter) (*models.SavedFilter, error)
+	GetFilter(ctx context.Context, name string) (*models.SavedFilter, error)
+	ListFilters(ctx context.Context) ([]*models.SavedFilter, error)
+	DeleteFilter(ctx context.Context, name string) error
+	// ResolveFilter compila la expresión y el ordenamiento de un filtro guardado
+	ResolveFilter(ctx context.Context, name string) (query.Expr, []models.SortField, error)
+}
+
+type savedFilterService struct {
+	repo repository.SavedFilterRepository
+}
+
+func NewSavedFilterService(repo repository.SavedFilterRepository) SavedFilterService {
+	return &savedFilterService{repo: repo}
+}
+
+// CreateFilter valida y guarda un nuevo filtro
+func (s *savedFilterService) CreateFilter(ctx context.Context, filter *models.SavedFilter) (*models.SavedFilter, error) {
+	if !datasetNameRegex.MatchString(filter.Name) {
+		return nil, errors.ErrInvalidSavedFilterName
+	}
+
+	if err := s.validate(filter); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter.CreatedAt = now
+	filter.UpdatedAt = now
+
+	return s.repo.Create(ctx, filter)
+}
+
+// UpdateFilter reemplaza la definición de un filtro existente conservando su
+// nombre y fecha de creación
+func (s *savedFilterService) UpdateFilter(ctx context.Context, name string, filter *models.SavedFilter) (*models.SavedFilter, error) {
+	if err := s.validate(filter); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Name = existing.Name
+	filter.CreatedAt = existing.CreatedAt
+	filter.UpdatedAt = time.Now()
+
+	return s.repo.Update(ctx, filter)
+}
+
+// GetFilter obtiene un filtro por su nombre
+func (s *savedFilterService) GetFilter(ctx context.Context, name string) (*models.SavedFilter, error) {
+	return s.repo.GetByName(ctx, name)
+}
+
+// ListFilters obtiene todos los filtros ordenados por nombre
+func (s *savedFilterService) ListFilters(ctx context.Context) ([]*models.SavedFilter, error) {
+	return s.repo.GetAll(ctx)
+}
+
+// DeleteFilter elimina un filtro
+func (s *savedFilterService) DeleteFilter(ctx context.Context, name string) error {
+	return s.repo.Delete(ctx, name)
+}
+
+// ResolveFilter compila la expresión y el ordenamiento de un filtro guardado
+func (s *savedFilterService) ResolveFilter(ctx context.Context, name string) (query.Expr, []models.SortField, error) {
+	filter, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return compileSavedFilter(filter)
+}
+
+// validate verifica que la expresión y el ordenamiento del filtro sean válidos
+func (s *savedFilterService) validate(filter *models.SavedFilter) error {
+	filter.Filter = strings.TrimSpace(filter.Filter)
+	filter.Sort = strings.TrimSpace(filter.Sort)
+
+	_, _, err := compileSavedFilter(filter)
+	return err
+}
+
+// compileSavedFilter compila la definición de un filtro guardado
+func compileSavedFilter(filter *models.SavedFilter) (query.Expr, []models.SortField, error) {
+	expr, err := query.Parse(filter.Filter)
+	if err != nil {
+		return nil, nil, errors.NewQueryError(err.Error())
+	}
+
+	sortFields, err := models.ParseSortFields(filter.Sort)
+	if err != nil {
+		return nil, nil, errors.NewValidationError("sort", err.Error())
+	}
+
+	return expr, sortFields, nil
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/utils"
+	"sort"
+	"strings"
+)
+
+// searchField conjunto de campos del cliente en los que aparece un término
+type searchField uint8
+
+const (
+	searchFieldClave searchField = 1 << iota
+	searchFieldNombre
+	searchFieldCorreo
+	searchFieldTelefono
+)
+
+// searchFieldWeights relevancia de una coincidencia en cada campo
+var searchFieldWeights = map[searchField]int{
+	searchFieldClave:    4,
+	searchFieldNombre:   3,
+	searchFieldCorreo:   2,
+	searchFieldTelefono: 2,
+}
+
+// searchIndex índice invertido para la búsqueda de texto libre. Los términos
+// se normalizan sin mayúsculas ni acentos y se guardan también ordenados para
+// resolver búsquedas por prefijo. Lo mantiene el dataset bajo su propio lock.
+//
+// Para que agregar y retirar un cliente no desplace la lista ordenada, los
+// términos nuevos se acumulan en pending y los que dejan de usarse conservan
+// una entrada vacía en postings; ambas listas se reorganizan cuando crecen en
+// proporción al índice.
+type searchIndex struct {
+	// postings clientes que contienen cada término, con los campos en que
+	// aparece. Un término sin clientes está pendiente de retirarse de terms.
+	postings map[string]map[int]searchField
+	// terms términos indexados en orden lexicográfico
+	terms []string
+	// pending términos nuevos aún no incorporados a terms, sin orden
+	pending []string
+	// stale términos de terms o pending que ya no tienen clientes
+	stale int
+	// clientTerms términos de cada cliente, para poder retirarlo del índice
+	clientTerms map[int][]string
+}
+
+// newSearchIndex construye el índice de búsqueda de clients
+func newSearchIndex(clients []*models.Client) *searchIndex {
+	idx := &searchIndex{
+		postings:    make(map[string]map[int]searchField),
+		clientTerms: make(map[int][]string, len(clients)),
+	}
+
+	for _, client := range clients {
+		idx.terms = append(idx.terms, idx.index(client)...)
+	}
+	sort.Strings(idx.terms)
+
+	return idx
+}
+
+// minPending términos pendientes que se admiten antes de reorganizar el índice
+// aunque sea pequeño
+const minPending = 256
+
+// add indexa un cliente
+func (idx *searchIndex) add(client *models.Client) {
+	idx.pending = append(idx.pending, idx.index(client)...)
+	if len(idx.pending) > minPending+len(idx.terms)/32 {
+		idx.rebuildTerms()
+	}
+}
+
+// remove elimina un cliente del índice
+func (idx *searchIndex) remove(client *models.Client) {
+	for _, term := range idx.clientTerms[client.ID] {
+		clients := idx.postings[term]
+		delete(clients, client.ID)
+		if len(clients) == 0 {
+			idx.stale++
+		}
+	}
+	delete(idx.clientTerms, client.ID)
+
+	if idx.stale > minPending+len(idx.terms)/2 {
+		idx.rebuildTerms()
+	}
+}
+
+// rebuildTerms incorpora los términos pendientes a terms y retira los que ya
+// no tienen clientes
+func (idx *searchIndex) rebuildTerms() {
+	inUse := func(terms []string) []string {
+		kept := make([]string, 0, len(terms))
+		for _, term := range terms {
+			if len(idx.postings[term]) > 0 {
+				kept = append(kept, term)
+			} else {
+				delete(idx.postings, term)
+			}
+		}
+		return kept
+	}
+
+	// terms ya está ordenado: basta ordenar los pendientes y mezclar
+	current := inUse(idx.terms)
+	pending := inUse(idx.pending)
+	sort.Strings(pending)
+
+	terms := make([]string, 0, len(current)+len(pending))
+	for len(current) > 0 && len(pending) > 0 {
+		if current[0] < pending[0] {
+			terms, current = append(terms, current[0]), current[1:]
+		} else {
+			terms, pending = append(terms, pending[0]), pending[1:]
+		}
+	}
+	terms = append(append(terms, current...), pending...)
+
+	idx.terms = terms
+	idx.pending = nil
+	idx.stale = 0
+}
+
+// index registra los términos de un cliente y devuelve los que no estaban
+// indexados, sin añadirlos a terms ni pending
+func (idx *searchIndex) index(client *models.Client) []string {
+	fields := map[string]searchField{}
+	for _, value := range []struct {
+		field searchField
+		text  string
+	}{
+		{searchFieldClave, client.Clave},
+		{searchFieldNombre, client.Nombre},
+		{searchFieldCorreo, client.Correo},
+		{searchFieldTelefono, utils.NormalizePhone(client.Telefono)},
+	} {
+		for _, term := range utils.Tokenize(value.text) {
+			fields[term] |= value.field
+		}
+	}
+
+	var newTerms []string
+	terms := make([]string, 0, len(fields))
+	for term, field := range fields {
+		clients, exists := idx.postings[term]
+		if !exists {
+			clients = make(map[int]searchField)
+			idx.postings[term] = clients
+			newTerms = append(newTerms, term)
+		} else if len(clients) == 0 {
+			// El término sigue en terms o pending; vuelve a estar en uso
+			idx.stale--
+		}
+		clients[client.ID] = field
+		terms = append(terms, term)
+	}
+	idx.clientTerms[client.ID] = terms
+
+	return newTerms
+}
+
+// search obtiene la puntuación de los clientes que contienen todas las
+// palabras de query, completas o como prefijo de un término. Una coincidencia
+// completa puntúa el doble que una por prefijo.
+func (idx *searchIndex) search(query string) map[int]int {
+	words := utils.Tokenize(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	var scores map[int]int
+	for _, word := range words {
+		wordScores := make(map[int]int)
+		score := func(term string) {
+			multiplier := 1
+			if term == word {
+				multiplier = 2
+			}
+
+			for id, fields := range idx.postings[term] {
+				// Por cada palabra cuenta solo el mejor término del cliente
+				wordScores[id] = max(wordScores[id], fields.weight()*multiplier)
+			}
+		}
+
+		for pos := sort.SearchStrings(idx.terms, word); pos < len(idx.terms); pos++ {
+			if !strings.HasPrefix(idx.terms[pos], word) {
+				break
+			}
+			score(idx.terms[pos])
+		}
+		for _, term := range idx.pending {
+			if strings.HasPrefix(term, word) {
+				score(term)
+			}
+		}
+
+		if scores == nil {
+			scores = wordScores
+			continue
+		}
+
+		// Solo siguen los clientes que contienen todas las palabras
+		for id, score := range scores {
+			if wordScore, found := wordScores[id]; found {
+				scores[id] = score + wordScore
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	return scores
+}
+
+// weight suma la relevancia de los campos del conjunto
+func (f searchField) weight() int {
+	total := 0
+	for field, weight := range searchFieldWeights {
+		if f&field != 0 {
+			total += weight
+		}
+	}
+	return total
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"client-data-compiler/internal/domain/models"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+var (
+	searchJose  = &models.Client{ID: 1, Clave: "P01", Nombre: "José Pérez", Correo: "jose@gmail.com", Telefono: "962 123 4567"}
+	searchPerla = &models.Client{ID: 2, Clave: "P02", Nombre: "Perla Gómez", Correo: "perla@hotmail.com"}
+	searchAna   = &models.Client{ID: 3, Clave: "PEREZ", Nombre: "Ana López", Correo: "ana@gmail.com"}
+	// searchJoseRenamed sustituye a searchJose con otro apellido
+	searchJoseRenamed = &models.Client{ID: 1, Clave: "P01", Nombre: "José Martínez", Correo: "jose@gmail.com"}
+)
+
+// searchStep operación sobre el índice: add o remove de un cliente
+type searchStep struct {
+	remove bool
+	client *models.Client
+}
+
+func addClients(clients ...*models.Client) []searchStep {
+	steps := make([]searchStep, len(clients))
+	for i, client := range clients {
+		steps[i] = searchStep{client: client}
+	}
+	return steps
+}
+
+// ranked IDs de scores de mayor a menor puntuación y, a igual puntuación, por ID
+func ranked(scores map[int]int) []int {
+	ids := make([]int, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+func TestSearchIndex(t *testing.T) {
+	all := addClients(searchJose, searchPerla, searchAna)
+
+	tests := []struct {
+		name  string
+		steps []searchStep
+		query string
+		want  []int
+	}{
+		{name: "sin acentos", steps: all, query: "perez", want: []int{3, 1}},
+		{name: "con acentos y mayúsculas", steps: all, query: "PÉREZ", want: []int{3, 1}},
+		{name: "prefijo", steps: all, query: "per", want: []int{2, 3, 1}},
+		{name: "prefijo con acento", steps: all, query: "gó", want: []int{2}},
+		{name: "no busca sufijos", steps: all, query: "ez", want: []int{}},
+		{name: "empate ordenado por ID", steps: all, query: "gmail", want: []int{1, 3}},
+		{name: "todas las palabras", steps: all, query: "jose perez", want: []int{1}},
+		{name: "palabras en distintos campos", steps: all, query: "perez ana", want: []int{3}},
+		{name: "teléfono normalizado", steps: all, query: "9621234", want: []int{1}},
+		{name: "sin palabras", steps: all, query: " - ", want: nil},
+		{
+			name:  "retirar",
+			steps: append(addClients(searchJose, searchPerla, searchAna), searchStep{remove: true, client: searchAna}),
+			query: "perez",
+			want:  []int{1},
+		},
+		{
+			name:  "retirar el único cliente de un término",
+			steps: []searchStep{{client: searchJose}, {remove: true, client: searchJose}},
+			query: "jose",
+			want:  []int{},
+		},
+		{
+			name:  "volver a agregar un término retirado",
+			steps: []searchStep{{client: searchJose}, {remove: true, client: searchJose}, {client: searchJose}},
+			query: "jose",
+			want:  []int{1},
+		},
+		{
+			name: "reemplazar quita los términos anteriores",
+			steps: append(addClients(searchJose, searchPerla, searchAna),
+				searchStep{remove: true, client: searchJose}, searchStep{client: searchJoseRenamed}),
+			query: "perez",
+			want:  []int{3},
+		},
+		{
+			name: "reemplazar indexa los términos nuevos",
+			steps: append(addClients(searchJose, searchPerla, searchAna),
+				searchStep{remove: true, client: searchJose}, searchStep{client: searchJoseRenamed}),
+			query: "martinez",
+			want:  []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		// Cada caso se comprueba con los términos nuevos pendientes y
+		// reorganizando el índice tras cada operación
+		for _, rebuild := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/reorganizado=%t", tt.name, rebuild), func(t *testing.T) {
+				idx := newSearchIndex(nil)
+				for _, step := range tt.steps {
+					if step.remove {
+						idx.remove(step.client)
+					} else {
+						idx.add(step.client)
+					}
+					if rebuild {
+						idx.rebuildTerms()
+					}
+				}
+
+				scores := idx.search(tt.query)
+				var got []int
+				if scores != nil {
+					got = ranked(scores)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("search(%q) = %v (%v), se esperaba %v", tt.query, got, scores, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestSearchIndexScores(t *testing.T) {
+	idx := newSearchIndex([]*models.Client{searchJose, searchPerla, searchAna})
+
+	tests := []struct {
+		query string
+		want  map[int]int
+	}{
+		// Clave completa (4×2) sobre nombre completo (3×2)
+		{"perez", map[int]int{1: 6, 3: 8}},
+		// Nombre y correo por prefijo (3+2) sobre clave por prefijo (4) y nombre por prefijo (3)
+		{"per", map[int]int{1: 3, 2: 5, 3: 4}},
+		// Suma de cada palabra: nombre y correo completos (5×2) y nombre completo (3×2)
+		{"jose perez", map[int]int{1: 16}},
+	}
+
+	for _, tt := range tests {
+		if got := idx.search(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("search(%q) = %v, se esperaba %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchIndexRebuild(t *testing.T) {
+	clients := benchmarkClients(2_000)
+	idx := newSearchIndex(clients[:1_000])
+
+	// Agregar y retirar suficientes clientes para que el índice se reorganice
+	// solo, en ambos sentidos
+	for _, client := range clients[1_000:] {
+		idx.add(client)
+	}
+	for _, client := range clients[:1_500] {
+		idx.remove(client)
+	}
+
+	if !sort.StringsAreSorted(idx.terms) {
+		t.Error("terms no está ordenado")
+	}
+	if len(idx.pending) > minPending+len(idx.terms)/32 || idx.stale > minPending+len(idx.terms)/2 {
+		t.Errorf("%d términos pendientes y %d sin clientes tras reorganizar %d términos", len(idx.pending), idx.stale, len(idx.terms))
+	}
+
+	idx.rebuildTerms()
+	for _, term := range idx.terms {
+		if len(idx.postings[term]) == 0 {
+			t.Fatalf("el término %q sin clientes sigue indexado", term)
+		}
+	}
+
+	// Solo quedan los clientes 1501 a 2000
+	if got := idx.search(clients[1_499].Clave); len(got) != 0 {
+		t.Errorf("search de un cliente retirado = %v", got)
+	}
+	if got := ranked(idx.search(clients[1_500].Clave)); !reflect.DeepEqual(got, []int{clients[1_500].ID}) {
+		t.Errorf("search de un cliente indexado = %v, se esperaba [%d]", got, clients[1_500].ID)
+	}
+	if got := idx.search("cliente1999"); len(got) != 1 {
+		t.Errorf("search(cliente1999) = %v, se esperaba un cliente", got)
+	}
+}
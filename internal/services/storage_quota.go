@@ -0,0 +1,86 @@
+package services
+
+import (
+	"client-data-compiler/internal/domain/errors"
+	"client-data-compiler/internal/tenant"
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"strings"
+)
+
+// StorageQuotas espacio máximo en bytes de los archivos subidos y exportados
+// de cada inquilino. Un valor no positivo significa sin límite.
+type StorageQuotas struct {
+	Default int64
+	// Tenants cuotas de los inquilinos que no usan Default
+	Tenants map[string]int64
+}
+
+// For obtiene la cuota del inquilino indicado
+func (q StorageQuotas) For(tenantID string) int64 {
+	if quota, exists := q.Tenants[tenantID]; exists && quota > 0 {
+		return quota
+	}
+	return q.Default
+}
+
+// reserveStorage reserva size bytes de la cuota del inquilino mientras se
+// guarda un archivo, para que las subidas simultáneas no la excedan entre
+// todas. Devuelve cuántos bytes puede ocupar como máximo el archivo y la
+// función que libera la reserva una vez guardado.
+func (s *uploadService) reserveStorage(ctx context.Context, tenantID string, size int64) (int64, func(), error) {
+	quota := s.quotas.For(tenantID)
+	if quota <= 0 {
+		return math.MaxInt64, func() {}, nil
+	}
+
+	s.quotaMu.Lock()
+	defer s.quotaMu.Unlock()
+
+	used, err := s.storageUsed(ctx, tenantID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	available := quota - used - s.reserved[tenantID]
+	if size > available {
+		return 0, nil, errors.NewStorageQuotaError(used, quota)
+	}
+
+	s.reserved[tenantID] += size
+	release := func() {
+		s.quotaMu.Lock()
+		defer s.quotaMu.Unlock()
+
+		s.reserved[tenantID] -= size
+		if s.reserved[tenantID] <= 0 {
+			delete(s.reserved, tenantID)
+		}
+	}
+
+	return available, release, nil
+}
+
+// storageUsed suma el tamaño de los archivos del inquilino en el almacén. Los
+// del inquilino por defecto contienen los de los demás en tenants/, que no se
+// cuentan.
+func (s *uploadService) storageUsed(ctx context.Context, tenantID string) (int64, error) {
+	objects, err := s.store.List(ctx, tenant.Prefix(uploadsPrefix, tenantID))
+	if err != nil {
+		return 0, errors.NewFileProcessingError(fmt.Sprintf("Error calculando el espacio utilizado: %v", err))
+	}
+
+	tenantsPrefix := path.Join(uploadsPrefix, "tenants") + "/"
+
+	var used int64
+	for _, object := range objects {
+		if tenantID == tenant.Default && strings.HasPrefix(object.Key, tenantsPrefix) {
+			continue
+		}
+		used += object.Size
+	}
+
+	return used, nil
+}
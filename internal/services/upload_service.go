@@ -0,0 +1,608 @@
+package services
+
+import (
+	"bytes"
+	"client-data-compiler/internal/domain/errors"
+	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/repository"
+	"client-data-compiler/internal/storage"
+	"client-data-compiler/internal/tenant"
+	"client-data-compiler/internal/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+type UploadService interface {
+	SaveUpload(ctx context.Context, src io.Reader, upload *models.Upload) (*models.Upload, error)
+	RegisterReupload(ctx context.Context, previous *models.Upload, upload *models.Upload) (*models.Upload, error)
+	FindDuplicateUpload(ctx context.Context, dataset, hash string) (*models.Upload, error)
+	FindStoredUpload(ctx context.Context, hash string) (*models.Upload, error)
+	ProcessUpload(ctx context.Context, upload *models.Upload, progress ProgressReporter) (*models.Upload, []*models.Client, error)
+	ProcessUploads(ctx context.Context, uploads []*models.Upload, progress ProgressReporter) []ProcessedUpload
+	GetUpload(ctx context.Context, id string) (*models.Upload, error)
+	GetUploadByStoredName(ctx context.Context, storedName string) (*models.Upload, error)
+	GetUploadFile(ctx context.Context, id string) (*models.Upload, *storage.Object, error)
+	ListUploads(ctx context.Context) ([]*models.Upload, error)
+	DeleteUpload(ctx context.Context, id string) error
+	ListFiles(ctx context.Context) ([]storage.ObjectInfo, error)
+	DeleteFile(ctx context.Context, name string) error
+	GetTemplate(ctx context.Context) (*storage.Object, error)
+	StorageUsage(ctx context.Context) (*models.StorageUsage, error)
+}
+
+const (
+	// uploadsPrefix prefijo de almacenamiento de los archivos subidos y
+	// exportados
+	uploadsPrefix = "uploads"
+	// templatesPrefix prefijo de almacenamiento de las plantillas
+	templatesPrefix = "templates"
+	// templateName nombre de la plantilla de carga de clientes
+	templateName = "plantilla_clientes.xlsx"
+)
+
+// ProcessedUpload resultado de procesar uno de los uploads de ProcessUploads
+type ProcessedUpload struct {
+	Upload *models.Upload
+	// Preview primeros clientes importados
+	Preview []*models.Client
+	Err     error
+}
+
+type uploadService struct {
+	clientService ClientService
+	uploadRepo    repository.UploadRepository
+	store         storage.Storage
+	fileWorkers   int
+	quotas        StorageQuotas
+	// maxUncompressed tamaño máximo descomprimido de un archivo subido
+	maxUncompressed int64
+
+	// reserved bytes reservados por inquilino para las subidas en curso
+	reserved map[string]int64
+	quotaMu  sync.Mutex
+}
+
+// NewUploadService crea el servicio de uploads, que guarda los archivos en
+// store bajo uploads/. fileWorkers limita cuántos
+// archivos de ProcessUploads se leen a la vez (1 si no es positivo) y quotas
+// el espacio que pueden ocupar los archivos de cada inquilino. Los archivos
+// que descomprimidos ocupan más de maxUncompressed bytes se rechazan.
+func NewUploadService(clientService ClientService, uploadRepo repository.UploadRepository, store storage.Storage, fileWorkers int, quotas StorageQuotas, maxUncompressed int64) UploadService {
+	if fileWorkers <= 0 {
+		fileWorkers = 1
+	}
+
+	return &uploadService{
+		clientService:   clientService,
+		uploadRepo:      uploadRepo,
+		store:           store,
+		fileWorkers:     fileWorkers,
+		quotas:          quotas,
+		maxUncompressed: maxUncompressed,
+		reserved:        make(map[string]int64),
+	}
+}
+
+// SaveUpload guarda el contenido del archivo en el almacén, entre los uploads
+// del inquilino de ctx, calculando su SHA-256 y tamaño, y lo registra con
+// estado "processing". upload.Size es el tamaño declarado, que se reserva de
+// la cuota del inquilino; si el archivo no cabe en la cuota falla con
+// STORAGE_QUOTA_EXCEEDED. Si el contenido no es un libro de Excel falla con
+// INVALID_FILE_FORMAT, y si descomprimido excede el máximo, con
+// ARCHIVE_TOO_LARGE. El contenido se verifica en un archivo temporal antes de
+// almacenarlo, de modo que nunca se almacena un archivo parcial o rechazado.
+func (s *uploadService) SaveUpload(ctx context.Context, src io.Reader, upload *models.Upload) (*models.Upload, error) {
+	upload.Tenant = tenant.FromContext(ctx)
+
+	available, release, err := s.reserveStorage(ctx, upload.Tenant, upload.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	start := time.Now()
+
+	tmp, err := os.CreateTemp("", "upload-*.xlsx")
+	if err != nil {
+		return nil, errors.NewFileProcessingError(fmt.Sprintf("Error guardando archivo: %v", err))
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	reader := utils.NewContextReader(ctx, src)
+	if available < math.MaxInt64 {
+		// Se lee un byte más de lo disponible para detectar que el archivo no cabe
+		reader = io.LimitReader(reader, available+1)
+	}
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), reader)
+	if err == nil && size > available {
+		used, _ := s.storageUsed(ctx, upload.Tenant)
+		return nil, errors.NewStorageQuotaError(used, s.quotas.For(upload.Tenant))
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewContextError(ctx.Err())
+		}
+		return nil, errors.NewFileProcessingError(fmt.Sprintf("Error guardando archivo: %v", err))
+	}
+
+	if err := s.verifyFile(ctx, tmp, size, upload.OriginalName); err != nil {
+		return nil, err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.NewFileProcessingError(fmt.Sprintf("Error guardando archivo: %v", err))
+	}
+	if err := s.store.Put(ctx, s.fileKey(upload), tmp, size); err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewContextError(ctx.Err())
+		}
+		return nil, errors.NewFileProcessingError(fmt.Sprintf("Error guardando archivo: %v", err))
+	}
+
+	upload.ID = utils.GenerateID()
+	upload.SHA256 = hex.EncodeToString(hasher.Sum(nil))
+	upload.Size = size
+	upload.UploadedAt = time.Now()
+	upload.Status = models.UploadStatusProcessing
+	upload.Timings = &models.StageTimings{SaveMs: time.Since(start).Milliseconds()}
+
+	registered, err := s.uploadRepo.Create(ctx, upload)
+	if err != nil {
+		s.store.Delete(context.WithoutCancel(ctx), s.fileKey(upload))
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Upload registrado", "upload_id", registered.ID,
+		"stored_name", registered.StoredName, "size", registered.Size, "sha256", registered.SHA256)
+
+	return registered, nil
+}
+
+// RegisterReupload registra un nuevo upload que reutiliza el archivo ya
+// almacenado de un upload previo con idéntico contenido
+func (s *uploadService) RegisterReupload(ctx context.Context, previous *models.Upload, upload *models.Upload) (*models.Upload, error) {
+	upload.ID = utils.GenerateID()
+	upload.Tenant = previous.Tenant
+	upload.StoredName = previous.StoredName
+	upload.SHA256 = previous.SHA256
+	upload.Size = previous.Size
+	upload.UploadedAt = time.Now()
+	upload.Status = models.UploadStatusProcessing
+
+	registered, err := s.uploadRepo.Create(ctx, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "Upload registrado reutilizando un archivo almacenado", "upload_id", registered.ID,
+		"previous_upload_id", previous.ID, "stored_name", registered.StoredName)
+
+	return registered, nil
+}
+
+// FindDuplicateUpload busca el upload procesado más reciente con el mismo
+// contenido cuyos clientes sigan cargados en el dataset indicado. Si el
+// dataset se reemplazó con otro archivo, se vació, se eliminó o se perdió al
+// reiniciar, el archivo debe volver a importarse y no hay duplicado.
+func (s *uploadService) FindDuplicateUpload(ctx context.Context, dataset, hash string) (*models.Upload, error) {
+	uploads, err := s.uploadRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// GetAll devuelve los uploads del más reciente al más antiguo
+	for _, upload := range uploads {
+		if upload.SHA256 != hash || upload.Dataset != dataset || upload.Status != models.UploadStatusCompleted {
+			continue
+		}
+		if s.clientService.HasClientsFromUpload(ctx, dataset, upload.ID) {
+			return upload, nil
+		}
+	}
+
+	return nil, errors.ErrUploadNotFound
+}
+
+// FindStoredUpload busca el upload más reciente con el mismo contenido cuyo
+// archivo siga almacenado, en cualquier dataset, para reutilizar el archivo
+// con RegisterReupload en lugar de guardarlo de nuevo
+func (s *uploadService) FindStoredUpload(ctx context.Context, hash string) (*models.Upload, error) {
+	uploads, err := s.uploadRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, upload := range uploads {
+		if upload.SHA256 != hash {
+			continue
+		}
+		// El archivo de los uploads fallidos, cancelados o vencidos pudo
+		// eliminarse aunque Stat aún lo encuentre a mitad de la limpieza
+		if upload.Status == models.UploadStatusFailed || upload.Status == models.UploadStatusCancelled ||
+			upload.Status == models.UploadStatusExpired {
+			continue
+		}
+		if _, err := s.store.Stat(ctx, s.fileKey(upload)); err != nil {
+			continue
+		}
+		return upload, nil
+	}
+
+	return nil, errors.ErrUploadNotFound
+}
+
+// ProcessUpload carga los clientes del archivo subido en su dataset y registra
+// el resultado, devolviendo una vista previa de los primeros clientes. Si el
+// procesamiento falla o se cancela el archivo se elimina, pero el registro se
+// conserva con estado "failed" o "cancelled". El registro se actualiza aunque
+// ctx haya terminado.
+func (s *uploadService) ProcessUpload(ctx context.Context, upload *models.Upload, progress ProgressReporter) (*models.Upload, []*models.Client, error) {
+	start := time.Now()
+
+	parsed, err := s.parseUpload(ctx, upload, progress)
+	if err == nil {
+		err = s.storeUpload(ctx, upload, parsed, progress)
+	}
+
+	return s.recordResult(ctx, upload, parsed, time.Since(start), err)
+}
+
+// ProcessUploads procesa varios uploads leyendo hasta fileWorkers archivos en
+// paralelo. Los clientes se almacenan en el orden de uploads, de modo que el
+// resultado es el mismo que procesarlos uno tras otro. Los resultados se
+// devuelven en ese mismo orden.
+func (s *uploadService) ProcessUploads(ctx context.Context, uploads []*models.Upload, progress ProgressReporter) []ProcessedUpload {
+	type parsedUpload struct {
+		clients *ParsedClients
+		elapsed time.Duration
+		err     error
+		done    chan struct{}
+	}
+
+	parsed := make([]*parsedUpload, len(uploads))
+	for i := range uploads {
+		parsed[i] = &parsedUpload{done: make(chan struct{})}
+	}
+
+	// Lanzar las lecturas en orden, con como máximo fileWorkers simultáneas.
+	// Cada archivo conserva su turno hasta que se almacena, de modo que nunca
+	// hay más de fileWorkers archivos leídos en memoria esperando su turno.
+	sem := make(chan struct{}, s.fileWorkers)
+	go func() {
+		for i, upload := range uploads {
+			sem <- struct{}{}
+			go func(p *parsedUpload, upload *models.Upload) {
+				defer close(p.done)
+
+				start := time.Now()
+				p.clients, p.err = s.parseUpload(ctx, upload, progress)
+				p.elapsed = time.Since(start)
+			}(parsed[i], upload)
+		}
+	}()
+
+	results := make([]ProcessedUpload, len(uploads))
+	for i, upload := range uploads {
+		p := parsed[i]
+		<-p.done
+
+		start := time.Now()
+		err := p.err
+		if err == nil {
+			err = s.storeUpload(ctx, upload, p.clients, progress)
+		}
+
+		updated, preview, err := s.recordResult(ctx, upload, p.clients, p.elapsed+time.Since(start), err)
+		results[i] = ProcessedUpload{Upload: updated, Preview: preview, Err: err}
+
+		// Liberar el turno: los clientes leídos ya están en el dataset o se
+		// descartaron, y no deben retenerse hasta terminar con el resto
+		p.clients = nil
+		<-sem
+	}
+
+	return results
+}
+
+// GetUpload obtiene los metadatos de un upload
+func (s *uploadService) GetUpload(ctx context.Context, id string) (*models.Upload, error) {
+	return s.uploadRepo.GetByID(ctx, id)
+}
+
+// GetUploadByStoredName obtiene los metadatos de un upload por su nombre almacenado
+func (s *uploadService) GetUploadByStoredName(ctx context.Context, storedName string) (*models.Upload, error) {
+	return s.uploadRepo.GetByStoredName(ctx, storedName)
+}
+
+// GetUploadFile obtiene un upload y abre su archivo almacenado; el llamador
+// debe cerrarlo. Si el archivo ya no existe (p. ej. falló su procesamiento)
+// devuelve UPLOAD_NOT_FOUND.
+func (s *uploadService) GetUploadFile(ctx context.Context, id string) (*models.Upload, *storage.Object, error) {
+	upload, err := s.uploadRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	object, err := s.store.Get(ctx, s.fileKey(upload))
+	if stderrors.Is(err, storage.ErrNotFound) {
+		return nil, nil, errors.ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, nil, errors.NewFileProcessingError(fmt.Sprintf("Error abriendo archivo: %v", err))
+	}
+
+	return upload, object, nil
+}
+
+// ListUploads obtiene todos los uploads registrados
+func (s *uploadService) ListUploads(ctx context.Context) ([]*models.Upload, error) {
+	return s.uploadRepo.GetAll(ctx)
+}
+
+// DeleteUpload elimina el archivo de un upload y su registro
+func (s *uploadService) DeleteUpload(ctx context.Context, id string) error {
+	upload, err := s.uploadRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.removeFileIfUnreferenced(ctx, upload); err != nil {
+		return err
+	}
+
+	return s.uploadRepo.Delete(ctx, id)
+}
+
+// ListFiles obtiene los archivos almacenados entre los uploads del inquilino
+// de ctx, sin los de sus subdirectorios (exportaciones y otros inquilinos)
+func (s *uploadService) ListFiles(ctx context.Context) ([]storage.ObjectInfo, error) {
+	prefix := s.uploadsPrefix(ctx)
+
+	objects, err := s.store.List(ctx, prefix)
+	if err != nil {
+		return nil, errors.NewFileProcessingError(fmt.Sprintf("Error leyendo archivos: %v", err))
+	}
+
+	files := make([]storage.ObjectInfo, 0, len(objects))
+	for _, object := range objects {
+		if path.Dir(object.Key) == prefix {
+			files = append(files, object)
+		}
+	}
+
+	return files, nil
+}
+
+// DeleteFile elimina un archivo almacenado del inquilino de ctx que no
+// pertenece a un upload registrado. Si no existe devuelve UPLOAD_NOT_FOUND.
+func (s *uploadService) DeleteFile(ctx context.Context, name string) error {
+	key := path.Join(s.uploadsPrefix(ctx), utils.SanitizeFilename(name))
+
+	if _, err := s.store.Stat(ctx, key); err != nil {
+		if stderrors.Is(err, storage.ErrNotFound) {
+			return errors.ErrUploadNotFound
+		}
+		return errors.NewFileProcessingError(fmt.Sprintf("Error eliminando archivo: %v", err))
+	}
+
+	if err := s.store.Delete(ctx, key); err != nil {
+		return errors.NewFileProcessingError(fmt.Sprintf("Error eliminando archivo: %v", err))
+	}
+
+	return nil
+}
+
+// GetTemplate abre la plantilla de carga de clientes; el llamador debe
+// cerrarla. Si el inquilino de ctx tiene una plantilla propia en
+// templates/tenants/<inquilino> se usa esa; si no, la común, que se crea si
+// no existe.
+func (s *uploadService) GetTemplate(ctx context.Context) (*storage.Object, error) {
+	tenantKey := path.Join(tenant.Prefix(templatesPrefix, tenant.FromContext(ctx)), templateName)
+	if object, err := s.store.Get(ctx, tenantKey); err == nil {
+		return object, nil
+	}
+
+	key := path.Join(templatesPrefix, templateName)
+	object, err := s.store.Get(ctx, key)
+	if stderrors.Is(err, storage.ErrNotFound) {
+		if err := s.createTemplate(ctx, key); err != nil {
+			return nil, err
+		}
+		object, err = s.store.Get(ctx, key)
+	}
+	if err != nil {
+		return nil, errors.NewFileProcessingError(fmt.Sprintf("Error abriendo plantilla: %v", err))
+	}
+
+	return object, nil
+}
+
+// StorageUsage obtiene el espacio ocupado por los archivos del inquilino de
+// ctx y su cuota
+func (s *uploadService) StorageUsage(ctx context.Context) (*models.StorageUsage, error) {
+	tenantID := tenant.FromContext(ctx)
+
+	used, err := s.storageUsed(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.StorageUsage{UsedBytes: used}
+	if quota := s.quotas.For(tenantID); quota > 0 {
+		usage.QuotaBytes = quota
+	}
+
+	return usage, nil
+}
+
+// Métodos auxiliares privados
+
+// uploadsPrefix prefijo de almacenamiento de los uploads del inquilino de ctx
+func (s *uploadService) uploadsPrefix(ctx context.Context) string {
+	return tenant.Prefix(uploadsPrefix, tenant.FromContext(ctx))
+}
+
+// fileKey obtiene la clave del archivo de un upload entre los de su inquilino
+func (s *uploadService) fileKey(upload *models.Upload) string {
+	return path.Join(tenant.Prefix(uploadsPrefix, upload.Tenant), upload.StoredName)
+}
+
+// verifyFile comprueba que el archivo recibido sea un libro de Excel y que no
+// sea una bomba zip
+func (s *uploadService) verifyFile(ctx context.Context, f io.ReaderAt, size int64, name string) error {
+	err := utils.VerifyXLSX(f, size, s.maxUncompressed)
+	switch {
+	case err == nil:
+		return nil
+	case stderrors.Is(err, utils.ErrXLSXTooLarge):
+		return errors.ErrArchiveTooLarge
+	default:
+		slog.WarnContext(ctx, "Archivo rechazado", "filename", name, "error", err)
+		return errors.ErrInvalidFileFormat
+	}
+}
+
+// createTemplate crea una plantilla básica de Excel con clientes de ejemplo
+func (s *uploadService) createTemplate(ctx context.Context, key string) error {
+	sampleClients := []*models.Client{
+		{
+			ID:       1,
+			Clave:    "1001",
+			Nombre:   "Juan Pérez García",
+			Correo:   "juan.perez@gmail.com",
+			Telefono: "9611234567",
+			IsValid:  true,
+		},
+		{
+			ID:       2,
+			Clave:    "1002",
+			Nombre:   "María López Hernández",
+			Correo:   "maria.lopez@hotmail.com",
+			Telefono: "9629876543",
+			IsValid:  true,
+		},
+		{
+			ID:       3,
+			Clave:    "1003",
+			Nombre:   "Carlos Rodríguez Méndez",
+			Correo:   "carlos.rodriguez@yahoo.com",
+			Telefono: "9675551234",
+			IsValid:  true,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewExcelService().WriteExcel(ctx, sampleClients, &buf); err != nil {
+		return err
+	}
+
+	if err := s.store.Put(ctx, key, &buf, int64(buf.Len())); err != nil {
+		return errors.NewFileProcessingError(fmt.Sprintf("Error creando plantilla: %v", err))
+	}
+
+	return nil
+}
+
+// parseUpload lee y valida los clientes del archivo de un upload. Si el
+// almacén no es local el archivo se descarga a uno temporal para leerlo.
+func (s *uploadService) parseUpload(ctx context.Context, upload *models.Upload, progress ProgressReporter) (*ParsedClients, error) {
+	filePath, cleanup, err := storage.LocalCopy(ctx, s.store, s.fileKey(upload))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewContextError(ctx.Err())
+		}
+		return nil, errors.NewFileProcessingError(fmt.Sprintf("Error abriendo archivo: %v", err))
+	}
+	defer cleanup()
+
+	return s.clientService.ParseClientsFromExcel(ctx, filePath, upload.ID, progress)
+}
+
+// storeUpload almacena los clientes de un upload en su dataset
+func (s *uploadService) storeUpload(ctx context.Context, upload *models.Upload, parsed *ParsedClients, progress ProgressReporter) error {
+	start := time.Now()
+	err := s.clientService.StoreClients(ctx, upload.Dataset, parsed, progress)
+	parsed.Timings.StoreMs = time.Since(start).Milliseconds()
+	return err
+}
+
+// recordResult registra en el upload el resultado de su procesamiento. Si err
+// no es nil, el archivo se elimina y el upload queda como "failed" o
+// "cancelled". elapsed es el tiempo de procesamiento, sin contar el guardado.
+// Devuelve la vista previa de los clientes importados.
+func (s *uploadService) recordResult(ctx context.Context, upload *models.Upload, parsed *ParsedClients, elapsed time.Duration, err error) (*models.Upload, []*models.Client, error) {
+	// El resultado debe registrarse aunque la carga se haya cancelado
+	recordCtx := context.WithoutCancel(ctx)
+
+	processedAt := time.Now()
+	upload.ProcessedAt = &processedAt
+
+	if parsed != nil {
+		timings := parsed.Timings
+		if upload.Timings != nil {
+			timings.SaveMs = upload.Timings.SaveMs
+		}
+		timings.TotalMs = timings.SaveMs + elapsed.Milliseconds()
+		upload.Timings = timings
+	}
+
+	if err != nil {
+		s.removeFileIfUnreferenced(recordCtx, upload)
+		upload.Status = models.UploadStatusFailed
+		if ctx.Err() == context.Canceled {
+			upload.Status = models.UploadStatusCancelled
+		}
+		upload.Error = err.Error()
+		if _, updateErr := s.uploadRepo.Update(recordCtx, upload); updateErr != nil {
+			slog.ErrorContext(ctx, "Error actualizando el registro del upload", "upload_id", upload.ID, "error", updateErr)
+		}
+		return upload, nil, err
+	}
+
+	upload.Status = models.UploadStatusCompleted
+	upload.TotalRows = parsed.Total
+	upload.ValidCount = parsed.Valid
+	upload.InvalidCount = parsed.Invalid
+
+	updated, err := s.uploadRepo.Update(recordCtx, upload)
+	if err != nil {
+		return upload, parsed.Preview, err
+	}
+
+	return updated, parsed.Preview, nil
+}
+
+// removeFileIfUnreferenced elimina el archivo de un upload salvo que otro upload
+// registrado (una reimportación del mismo contenido) lo siga utilizando
+func (s *uploadService) removeFileIfUnreferenced(ctx context.Context, upload *models.Upload) error {
+	uploads, err := s.uploadRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range uploads {
+		if other.ID != upload.ID && other.StoredName == upload.StoredName &&
+			other.Status != models.UploadStatusFailed {
+			return nil
+		}
+	}
+
+	if err := s.store.Delete(ctx, s.fileKey(upload)); err != nil {
+		return errors.NewFileProcessingError(fmt.Sprintf("Error eliminando archivo: %v", err))
+	}
+
+	return nil
+}
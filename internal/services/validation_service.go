@@ -1,25 +1,59 @@
 package services
 
 import (
+	"client-data-compiler/internal/domain/errors"
 	"client-data-compiler/internal/domain/models"
+	"client-data-compiler/internal/tenant"
 	"client-data-compiler/internal/utils"
+	"context"
+	"runtime"
 	"sync"
 )
 
 type ValidationService interface {
-	ValidateClient(client *models.Client) *models.Client
-	ValidateClients(clients []*models.Client) []*models.Client
-	ValidateClientsConcurrent(clients []*models.Client) []*models.Client
+	ValidateClient(ctx context.Context, client *models.Client) *models.Client
+	ValidateClients(ctx context.Context, clients []*models.Client) []*models.Client
+	ValidateClientsConcurrent(ctx context.Context, clients []*models.Client, progress ProgressReporter) ([]*models.Client, error)
+	ValidateBatch(ctx context.Context, clients []*models.Client, progress ProgressReporter) error
 }
 
-type validationService struct{}
+type validationService struct {
+	workers             int
+	concurrentThreshold int
+	// defaultRules reglas de los inquilinos sin reglas propias
+	defaultRules models.ValidationRules
+	// tenantRules reglas de los inquilinos que no usan las reglas por defecto
+	tenantRules map[string]models.ValidationRules
+}
 
-func NewValidationService() ValidationService {
-	return &validationService{}
+// NewValidationService crea el servicio de validación. Los bloques con al
+// menos concurrentThreshold clientes se validan con workers goroutines; los
+// valores no positivos usan GOMAXPROCS y 100 respectivamente. tenantRules
+// contiene las reglas propias de cada inquilino del contexto y defaultRules
+// las del resto; sus listas vacías usan las de utils.
+func NewValidationService(workers, concurrentThreshold int, defaultRules models.ValidationRules, tenantRules map[string]models.ValidationRules) ValidationService {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if concurrentThreshold <= 0 {
+		concurrentThreshold = 100
+	}
+
+	return &validationService{
+		workers:             workers,
+		concurrentThreshold: concurrentThreshold,
+		defaultRules:        defaultRules,
+		tenantRules:         tenantRules,
+	}
 }
 
-// ValidateClient valida un cliente individual
-func (s *validationService) ValidateClient(client *models.Client) *models.Client {
+// ValidateClient valida un cliente individual con las reglas del inquilino de ctx
+func (s *validationService) ValidateClient(ctx context.Context, client *models.Client) *models.Client {
+	return s.validateClient(client, s.rulesFor(ctx))
+}
+
+// validateClient valida un cliente con las reglas indicadas
+func (s *validationService) validateClient(client *models.Client, rules *resolvedRules) *models.Client {
 	// Limpiar errores previos
 	client.ClearErrors()
 
@@ -36,13 +70,13 @@ func (s *validationService) ValidateClient(client *models.Client) *models.Client
 
 	// Validar correo
 	client.Correo = utils.CleanString(client.Correo)
-	if valid, msg := utils.ValidateEmail(client.Correo); !valid {
+	if valid, msg := utils.ValidateEmailDomains(client.Correo, rules.emailDomains); !valid {
 		client.AddError("correo", msg)
 	}
 
 	// Validar teléfono
 	client.Telefono = utils.CleanString(client.Telefono)
-	if valid, msg := utils.ValidatePhone(client.Telefono); !valid {
+	if valid, msg := utils.ValidatePhoneAreaCodes(client.Telefono, rules.phoneAreaCodes, rules.region); !valid {
 		client.AddError("telefono", msg)
 	}
 
@@ -53,29 +87,55 @@ func (s *validationService) ValidateClient(client *models.Client) *models.Client
 }
 
 // ValidateClients valida múltiples clientes secuencialmente
-func (s *validationService) ValidateClients(clients []*models.Client) []*models.Client {
+func (s *validationService) ValidateClients(ctx context.Context, clients []*models.Client) []*models.Client {
 	validatedClients := make([]*models.Client, len(clients))
+	rules := s.rulesFor(ctx)
 
 	for i, client := range clients {
-		validatedClients[i] = s.ValidateClient(client)
+		validatedClients[i] = s.validateClient(client, rules)
 	}
 
 	return validatedClients
 }
 
-// ValidateClientsConcurrent valida múltiples clientes usando goroutines para mejor rendimiento
-func (s *validationService) ValidateClientsConcurrent(clients []*models.Client) []*models.Client {
+// ValidateClientsConcurrent valida múltiples clientes usando goroutines para mejor rendimiento,
+// reportando el avance y deteniéndose si ctx se cancela
+func (s *validationService) ValidateClientsConcurrent(ctx context.Context, clients []*models.Client, progress ProgressReporter) ([]*models.Client, error) {
+	progress = progressOrNoop(progress)
+	progress.Stage(models.StageValidating)
+
+	if err := s.ValidateBatch(ctx, clients, progress); err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+// ValidateBatch valida en su lugar un bloque de clientes, sin reportar cambio
+// de etapa. Se usa para validar los bloques de una lectura en streaming.
+func (s *validationService) ValidateBatch(ctx context.Context, clients []*models.Client, progress ProgressReporter) error {
+	progress = progressOrNoop(progress)
+
 	if len(clients) == 0 {
-		return clients
+		return nil
 	}
 
+	rules := s.rulesFor(ctx)
+
 	// Para pocos clientes, usar validación secuencial
-	if len(clients) < 100 {
-		return s.ValidateClients(clients)
+	if len(clients) < s.concurrentThreshold || s.workers == 1 {
+		for _, client := range clients {
+			if ctx.Err() != nil {
+				return errors.NewContextError(ctx.Err())
+			}
+			s.validateClient(client, rules)
+			progress.RowsValidated(1, invalidCount(client))
+		}
+		return nil
 	}
 
 	// Usar workers para validación concurrente
-	numWorkers := 10
+	numWorkers := s.workers
 	if len(clients) < numWorkers {
 		numWorkers = len(clients)
 	}
@@ -89,7 +149,11 @@ func (s *validationService) ValidateClientsConcurrent(clients []*models.Client)
 		go func() {
 			defer wg.Done()
 			for index := range jobs {
-				clients[index] = s.ValidateClient(clients[index])
+				if ctx.Err() != nil {
+					continue // Vaciar la cola sin validar
+				}
+				clients[index] = s.validateClient(clients[index], rules)
+				progress.RowsValidated(1, invalidCount(clients[index]))
 			}
 		}()
 	}
@@ -103,7 +167,56 @@ func (s *validationService) ValidateClientsConcurrent(clients []*models.Client)
 	// Esperar a que terminen todos los workers
 	wg.Wait()
 
-	return clients
+	if ctx.Err() != nil {
+		return errors.NewContextError(ctx.Err())
+	}
+
+	return nil
+}
+
+// resolvedRules reglas de validación con los valores por defecto aplicados
+type resolvedRules struct {
+	region         string
+	phoneAreaCodes []string
+	emailDomains   []string
+}
+
+// rulesFor obtiene las reglas del inquilino de ctx
+func (s *validationService) rulesFor(ctx context.Context) *resolvedRules {
+	resolved := &resolvedRules{
+		region:         utils.DefaultRegion,
+		phoneAreaCodes: utils.DefaultPhoneAreaCodes,
+		emailDomains:   utils.DefaultEmailDomains,
+	}
+	resolved.apply(s.defaultRules)
+
+	if rules, exists := s.tenantRules[tenant.FromContext(ctx)]; exists {
+		resolved.apply(rules)
+	}
+
+	return resolved
+}
+
+// apply sustituye las reglas resueltas por las listas no vacías de rules
+func (resolved *resolvedRules) apply(rules models.ValidationRules) {
+	if len(rules.PhoneAreaCodes) > 0 {
+		resolved.phoneAreaCodes = rules.PhoneAreaCodes
+		resolved.region = rules.Region
+		if resolved.region == "" {
+			resolved.region = "la región configurada"
+		}
+	}
+	if len(rules.EmailDomains) > 0 {
+		resolved.emailDomains = rules.EmailDomains
+	}
+}
+
+// invalidCount devuelve 1 si el cliente tiene errores, para reportar avance
+func invalidCount(client *models.Client) int {
+	if client.IsValid {
+		return 0
+	}
+	return 1
 }
 
 // GetValidationStats obtiene estadísticas de validación
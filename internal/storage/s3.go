@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Options conexión a un bucket compatible con S3 (AWS, MinIO, etc.)
+type S3Options struct {
+	// Endpoint URL del servicio, p. ej. http://localhost:9000; si está vacío
+	// se usa el de AWS para Region
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix prefijo opcional de todas las claves dentro del bucket
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle direcciona el bucket en la ruta (endpoint/bucket/clave) en
+	// lugar de en el host (bucket.endpoint/clave), como requieren MinIO y la
+	// mayoría de los servicios compatibles
+	PathStyle bool
+}
+
+// s3Storage guarda los archivos en un bucket S3 usando su API REST
+type s3Storage struct {
+	endpoint  *url.URL
+	bucket    string
+	prefix    string
+	pathStyle bool
+	signer    *sigV4Signer
+	client    *http.Client
+}
+
+// NewS3 crea un almacenamiento en un bucket compatible con S3
+func NewS3(opts S3Options) (Storage, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("falta el bucket de S3")
+	}
+	if opts.AccessKeyID == "" || opts.SecretAccessKey == "" {
+		return nil, errors.New("faltan las credenciales de S3")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", opts.Region)
+	}
+
+	endpoint, err := url.Parse(strings.TrimSuffix(opts.Endpoint, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("endpoint de S3 inválido: %q", opts.Endpoint)
+	}
+
+	return &s3Storage{
+		endpoint:  endpoint,
+		bucket:    opts.Bucket,
+		prefix:    strings.Trim(opts.Prefix, "/"),
+		pathStyle: opts.PathStyle,
+		signer: &sigV4Signer{
+			accessKeyID:     opts.AccessKeyID,
+			secretAccessKey: opts.SecretAccessKey,
+			region:          opts.Region,
+			service:         "s3",
+		},
+		client: &http.Client{},
+	}, nil
+}
+
+func (s *s3Storage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, objectKey, nil, r)
+	if err != nil {
+		return err
+	}
+	// S3 no admite cuerpos chunked sin firmar cada bloque
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *s3Storage) Get(ctx context.Context, key string) (*Object, error) {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodGet, objectKey, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Object{ObjectInfo: objectInfo(key, resp), Body: resp.Body}, nil
+}
+
+func (s *s3Storage) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodHead, objectKey, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	info := objectInfo(key, resp)
+	return &info, nil
+}
+
+// listBucketResult respuesta de ListObjectsV2
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+func (s *s3Storage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	cleaned, err := cleanKey(prefix)
+	if err != nil {
+		return nil, err
+	}
+	listPrefix := path.Join(s.prefix, cleaned) + "/"
+
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {listPrefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		req, err := s.newRequest(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := s.do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("respuesta de S3 inválida: %w", err)
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:     strings.TrimPrefix(strings.TrimPrefix(content.Key, s.prefix), "/"),
+				Size:    content.Size,
+				ModTime: content.LastModified,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	objectKey, err := s.objectKey(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodDelete, objectKey, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// objectKey obtiene la clave dentro del bucket, con el prefijo configurado
+func (s *s3Storage) objectKey(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(s.prefix, cleaned), nil
+}
+
+// newRequest crea una petición sobre objectKey, o sobre el bucket si está vacía
+func (s *s3Storage) newRequest(ctx context.Context, method, objectKey string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := *s.endpoint
+	objectPath, rawObjectPath := "/"+objectKey, "/"+uriEncode(objectKey, false)
+	if s.pathStyle {
+		objectPath = "/" + s.bucket + objectPath
+		rawObjectPath = "/" + uriEncode(s.bucket, true) + rawObjectPath
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(s.endpoint.Path, "/") + objectPath
+	u.RawPath = strings.TrimSuffix(s.endpoint.EscapedPath(), "/") + rawObjectPath
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// s3Error cuerpo de las respuestas de error de S3
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// do firma y envía la petición. Las respuestas 404 de un archivo se
+// devuelven como ErrNotFound y el resto de las no 2xx como error con el código de S3.
+func (s *s3Storage) do(req *http.Request) (*http.Response, error) {
+	s.signer.sign(req, unsignedPayload, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var apiErr s3Error
+	xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&apiErr)
+
+	// Un bucket inexistente es un error de configuración, no un archivo que falta
+	if resp.StatusCode == http.StatusNotFound && apiErr.Code != "NoSuchBucket" {
+		return nil, ErrNotFound
+	}
+	if apiErr.Code == "" {
+		apiErr.Code = resp.Status
+	}
+	return nil, fmt.Errorf("S3 %s %s: %s %s", req.Method, req.URL.Path, apiErr.Code, apiErr.Message)
+}
+
+// objectInfo obtiene los metadatos de las cabeceras de una respuesta
+func objectInfo(key string, resp *http.Response) ObjectInfo {
+	info := ObjectInfo{Key: key, Size: resp.ContentLength}
+	if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		info.Size = size
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modTime
+	}
+	return info
+}
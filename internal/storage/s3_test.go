@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 servidor compatible con el subconjunto de la API de S3 que usa
+// s3Storage, con direccionamiento por ruta (endpoint/bucket/clave) como MinIO
+type fakeS3 struct {
+	bucket string
+	// pageSize objetos por página de ListObjectsV2, pequeño para forzar la paginación
+	pageSize int
+	modTime  time.Time
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	// listRequests páginas pedidas a ListObjectsV2
+	listRequests int
+}
+
+func newFakeS3(t *testing.T, bucket string) (*fakeS3, *httptest.Server) {
+	fake := &fakeS3{
+		bucket:   bucket,
+		pageSize: 2,
+		modTime:  time.Date(2025, 5, 25, 12, 0, 0, 0, time.UTC),
+		objects:  make(map[string][]byte),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), sigV4Algorithm+" Credential=test-key/") ||
+		r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") != unsignedPayload {
+		f.writeError(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		f.writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case key == "" && r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		f.list(w, r)
+
+	case r.Method == http.MethodPut:
+		if r.ContentLength < 0 {
+			f.writeError(w, http.StatusLengthRequired, "MissingContentLength")
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			f.writeError(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = body
+		w.WriteHeader(http.StatusOK)
+
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		body, exists := f.objects[key]
+		if !exists {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			f.writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Header().Set("Last-Modified", f.modTime.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+
+	case r.Method == http.MethodDelete:
+		// S3 responde 204 aunque el objeto no exista
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		f.writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// list responde a ListObjectsV2. El token de continuación es la última clave
+// de la página anterior.
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	f.listRequests++
+	prefix := r.URL.Query().Get("prefix")
+	after := r.URL.Query().Get("continuation-token")
+
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var result struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		IsTruncated           bool     `xml:"IsTruncated"`
+		NextContinuationToken string   `xml:"NextContinuationToken,omitempty"`
+		Contents              []struct {
+			Key          string `xml:"Key"`
+			Size         int    `xml:"Size"`
+			LastModified string `xml:"LastModified"`
+		} `xml:"Contents"`
+	}
+	if len(keys) > f.pageSize {
+		keys = keys[:f.pageSize]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[len(keys)-1]
+	}
+	for _, key := range keys {
+		result.Contents = append(result.Contents, struct {
+			Key          string `xml:"Key"`
+			Size         int    `xml:"Size"`
+			LastModified string `xml:"LastModified"`
+		}{key, len(f.objects[key]), f.modTime.Format(time.RFC3339)})
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func newTestS3(t *testing.T, endpoint, bucket string) Storage {
+	store, err := NewS3(S3Options{
+		Endpoint:        endpoint,
+		Bucket:          bucket,
+		Prefix:          "/app/",
+		AccessKeyID:     "test-key",
+		SecretAccessKey: "test-secret",
+		PathStyle:       true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func putString(t *testing.T, store Storage, key, content string) {
+	t.Helper()
+	if err := store.Put(context.Background(), key, strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Put(%s): %v", key, err)
+	}
+}
+
+func TestS3PutGetStat(t *testing.T) {
+	fake, server := newFakeS3(t, "clientes")
+	store := newTestS3(t, server.URL, "clientes")
+	ctx := context.Background()
+
+	putString(t, store, "uploads/tenants/tapachula/a b.xlsx", "contenido")
+	putString(t, store, "exports/vacio.xlsx", "")
+
+	if _, exists := fake.objects["app/uploads/tenants/tapachula/a b.xlsx"]; !exists {
+		t.Fatalf("el objeto no se guardó con el prefijo; claves: %v", fake.objects)
+	}
+
+	object, err := store.Get(ctx, "uploads/tenants/tapachula/a b.xlsx")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	body, err := io.ReadAll(object.Body)
+	object.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "contenido" {
+		t.Errorf("Get body = %q", body)
+	}
+	if object.Size != int64(len("contenido")) || !object.ModTime.Equal(fake.modTime) {
+		t.Errorf("Get info = %+v", object.ObjectInfo)
+	}
+
+	info, err := store.Stat(ctx, "exports/vacio.xlsx")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Key != "exports/vacio.xlsx" || info.Size != 0 || !info.ModTime.Equal(fake.modTime) {
+		t.Errorf("Stat = %+v", info)
+	}
+}
+
+func TestS3NotFound(t *testing.T) {
+	_, server := newFakeS3(t, "clientes")
+	store := newTestS3(t, server.URL, "clientes")
+	ctx := context.Background()
+
+	if _, err := store.Get(ctx, "uploads/no-existe.xlsx"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get de un objeto inexistente: %v, se esperaba ErrNotFound", err)
+	}
+	if _, err := store.Stat(ctx, "uploads/no-existe.xlsx"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat de un objeto inexistente: %v, se esperaba ErrNotFound", err)
+	}
+
+	// Un bucket inexistente es un error de configuración
+	wrongBucket := newTestS3(t, server.URL, "otro")
+	_, err := wrongBucket.Get(ctx, "uploads/a.xlsx")
+	if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "NoSuchBucket") {
+		t.Errorf("Get en un bucket inexistente: %v, se esperaba NoSuchBucket", err)
+	}
+}
+
+func TestS3ListPaginates(t *testing.T) {
+	fake, server := newFakeS3(t, "clientes")
+	store := newTestS3(t, server.URL, "clientes")
+
+	want := []string{
+		"uploads/1.xlsx",
+		"uploads/2.xlsx",
+		"uploads/3.xlsx",
+		"uploads/tenants/tapachula/4.xlsx",
+		"uploads/tenants/tapachula/5 con espacios.xlsx",
+	}
+	for _, key := range want {
+		putString(t, store, key, key)
+	}
+	// Fuera del prefijo listado
+	putString(t, store, "uploads-old/6.xlsx", "x")
+	putString(t, store, "exports/7.xlsx", "x")
+
+	objects, err := store.List(context.Background(), "uploads")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	var got []string
+	for _, object := range objects {
+		got = append(got, object.Key)
+		if object.Size != int64(len(object.Key)) || !object.ModTime.Equal(fake.modTime) {
+			t.Errorf("List %s = %+v", object.Key, object)
+		}
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("List = %v, se esperaba %v", got, want)
+	}
+	if fake.listRequests != 3 {
+		t.Errorf("List pidió %d páginas, se esperaban 3", fake.listRequests)
+	}
+}
+
+func TestS3Delete(t *testing.T) {
+	_, server := newFakeS3(t, "clientes")
+	store := newTestS3(t, server.URL, "clientes")
+	ctx := context.Background()
+
+	putString(t, store, "exports/a.xlsx", "a")
+
+	if err := store.Delete(ctx, "exports/a.xlsx"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, "exports/a.xlsx"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat tras Delete: %v, se esperaba ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "exports/a.xlsx"); err != nil {
+		t.Errorf("Delete de un objeto inexistente: %v", err)
+	}
+}
+
+func TestS3RejectsInvalidKeys(t *testing.T) {
+	_, server := newFakeS3(t, "clientes")
+	store := newTestS3(t, server.URL, "clientes")
+
+	for _, key := range []string{"", "../secreto", "/absoluta", "a\\b"} {
+		err := store.Put(context.Background(), key, bytes.NewReader(nil), 0)
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q): %v, se esperaba ErrInvalidKey", key, err)
+		}
+	}
+}
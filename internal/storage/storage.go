@@ -0,0 +1,108 @@
+// Package storage almacena los archivos subidos, exportados y las plantillas.
+// Los archivos se identifican con claves relativas separadas por "/" (p. ej.
+// "uploads/tenants/tapachula/<id>.xlsx") y se guardan en el sistema de
+// archivos local o en un bucket compatible con S3 según la configuración.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound el archivo indicado no existe
+	ErrNotFound = errors.New("el archivo no existe")
+	// ErrInvalidKey la clave está vacía, es absoluta o sale de la raíz con ".."
+	ErrInvalidKey = errors.New("clave de archivo inválida")
+)
+
+// Storage almacén de archivos
+type Storage interface {
+	// Put guarda el contenido de r, de size bytes, con la clave indicada,
+	// reemplazando el archivo que hubiera
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get abre un archivo; el llamador debe cerrar Body
+	Get(ctx context.Context, key string) (*Object, error)
+	// Stat obtiene el tamaño y la fecha de modificación de un archivo
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// List obtiene los archivos cuya clave empieza por prefix + "/",
+	// incluidos los de subdirectorios
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Delete elimina un archivo; eliminar uno que no existe no es un error
+	Delete(ctx context.Context, key string) error
+}
+
+// ObjectInfo metadatos de un archivo almacenado
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Object archivo abierto con Get
+type Object struct {
+	ObjectInfo
+	Body io.ReadCloser
+}
+
+// cleanKey normaliza una clave y rechaza las que podrían salir de la raíz
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean(key)
+	if key == "" || cleaned == "." || strings.HasPrefix(cleaned, "/") ||
+		cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+// LocalCopy obtiene una ruta local con el contenido de un archivo, para las
+// bibliotecas que solo leen del disco. Con el almacenamiento local es la ruta
+// del propio archivo; con los demás, una copia temporal que cleanup elimina.
+func LocalCopy(ctx context.Context, store Storage, key string) (filePath string, cleanup func(), err error) {
+	if local, ok := store.(*localStorage); ok {
+		filePath, err := local.path(key)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, err := os.Stat(filePath); err != nil {
+			return "", nil, notFoundOr(err)
+		}
+		return filePath, func() {}, nil
+	}
+
+	object, err := store.Get(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+	defer object.Body.Close()
+
+	tmp, err := os.CreateTemp("", "storage-*"+path.Ext(key))
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { os.Remove(tmp.Name()) }
+
+	_, err = io.Copy(tmp, object.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return tmp.Name(), cleanup, nil
+}
+
+// notFoundOr traduce los errores de archivo inexistente a ErrNotFound
+func notFoundOr(err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+// Package tenant identifica al inquilino (sucursal) al que pertenecen los
+// datos de una petición. El inquilino viaja en el contexto desde el
+// middleware de autenticación hasta los repositorios, que lo usan para
+// aislar los datos de cada uno.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// Default inquilino de los principales sin inquilino asignado y de los datos
+// creados antes de la separación por inquilinos
+const Default = "default"
+
+// idRegex define los identificadores de inquilino permitidos
+var idRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,63}$`)
+
+type contextKey struct{}
+
+// WithTenant devuelve una copia de ctx asociada al inquilino indicado
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext obtiene el inquilino de ctx, o Default si no tiene
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(contextKey{}).(string); ok && id != "" {
+		return id
+	}
+	return Default
+}
+
+// Validate verifica que el identificador de inquilino sea válido
+func Validate(id string) error {
+	if !idRegex.MatchString(id) {
+		return fmt.Errorf("identificador de inquilino inválido '%s'", id)
+	}
+	return nil
+}
+
+// Normalize devuelve Default para el identificador vacío de los registros
+// anteriores a la separación por inquilinos
+func Normalize(id string) string {
+	if id == "" {
+		return Default
+	}
+	return id
+}
+
+// Prefix obtiene el prefijo de las claves de almacenamiento del inquilino
+// dentro de base. El inquilino por defecto usa base directamente para
+// conservar los archivos existentes.
+func Prefix(base, id string) string {
+	id = Normalize(id)
+	if id == Default {
+		return base
+	}
+	return path.Join(base, "tenants", id)
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxFilenameLength longitud máxima de un nombre saneado
+const maxFilenameLength = 128
+
+// SanitizeFilename reduce un nombre de archivo recibido del usuario a letras y
+// números ASCII, '.', '-' y '_'; cualquier otro carácter se reemplaza por
+// '_'. Se descartan los directorios y los puntos iniciales, de modo que el
+// resultado nunca es una ruta ni un archivo oculto. Si no queda nada devuelve
+// "archivo".
+func SanitizeFilename(filename string) string {
+	// Los nombres pueden venir con separadores de Windows
+	filename = filepath.Base(strings.ReplaceAll(filename, "\\", "/"))
+
+	var b strings.Builder
+	for _, r := range filename {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+
+	sanitized := strings.TrimLeft(b.String(), ".")
+	if len(sanitized) > maxFilenameLength {
+		sanitized = sanitized[len(sanitized)-maxFilenameLength:]
+	}
+	if sanitized == "" || sanitized == "_" {
+		return "archivo"
+	}
+
+	return sanitized
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// GenerateID genera un identificador aleatorio opaco en hexadecimal
+func GenerateID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("no se pudo generar un identificador aleatorio: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// IsGeneratedID indica si id tiene el formato de GenerateID, de modo que puede
+// usarse como nombre de archivo sin riesgo
+func IsGeneratedID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
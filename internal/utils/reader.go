@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"context"
+	"io"
+)
+
+// contextReader lector que deja de leer cuando su contexto termina
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// NewContextReader envuelve r para que cada lectura falle con el error de ctx
+// una vez que este se cancela o vence su plazo
+func NewContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r}
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
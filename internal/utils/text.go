@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// FoldText normaliza un texto para compararlo sin distinguir mayúsculas ni
+// acentos: "Pérez" y "PEREZ" producen "perez"
+func FoldText(text string) string {
+	// La cadena de transformaciones guarda estado, así que se crea en cada llamada
+	folder := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+	folded, _, err := transform.String(folder, text)
+	if err != nil {
+		folded = text
+	}
+
+	return strings.ToLower(folded)
+}
+
+// Tokenize divide un texto normalizado con FoldText en palabras formadas por
+// letras y dígitos
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(FoldText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
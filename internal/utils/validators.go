@@ -44,25 +44,41 @@ func ValidateClientName(nombre string) (bool, string) {
 	return true, ""
 }
 
+// DefaultEmailDomains dominios de correo permitidos si no se configuran otros
+var DefaultEmailDomains = []string{
+	"gmail.com",
+	"hotmail.com",
+	"outlook.com",
+	"yahoo.com",
+	"live.com",
+	"icloud.com",
+	"msn.com",
+}
+
+// DefaultPhoneAreaCodes ladas permitidas si no se configuran otras (Chiapas)
+var DefaultPhoneAreaCodes = []string{
+	"916", "917", "918", "919", "932", "934",
+	"961", "962", "963", "964", "965", "966",
+	"967", "968", "992", "994",
+}
+
+// DefaultRegion región de las ladas por defecto, usada en los mensajes
+const DefaultRegion = "Chiapas"
+
 // ValidateEmail valida que el correo tenga un formato válido y dominio permitido
 func ValidateEmail(correo string) (bool, string) {
+	return ValidateEmailDomains(correo, DefaultEmailDomains)
+}
+
+// ValidateEmailDomains valida que el correo tenga un formato válido y uno de
+// los dominios indicados
+func ValidateEmailDomains(correo string, allowedDomains []string) (bool, string) {
 	correo = strings.TrimSpace(strings.ToLower(correo))
 
 	if correo == "" {
 		return false, "El correo no puede estar vacío"
 	}
 
-	// Dominios permitidos
-	allowedDomains := []string{
-		"@gmail.com",
-		"@hotmail.com",
-		"@outlook.com",
-		"@yahoo.com",
-		"@live.com",
-		"@icloud.com",
-		"@msn.com",
-	}
-
 	// Verificar formato básico de email
 	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 	if !emailRegex.MatchString(correo) {
@@ -72,14 +88,14 @@ func ValidateEmail(correo string) (bool, string) {
 	// Verificar dominio permitido
 	validDomain := false
 	for _, domain := range allowedDomains {
-		if strings.HasSuffix(correo, domain) {
+		if strings.HasSuffix(correo, "@"+strings.ToLower(domain)) {
 			validDomain = true
 			break
 		}
 	}
 
 	if !validDomain {
-		return false, "El dominio del correo no está permitido. Use: gmail.com, hotmail.com, outlook.com, yahoo.com, live.com, icloud.com, msn.com"
+		return false, "El dominio del correo no está permitido. Use: " + strings.Join(allowedDomains, ", ")
 	}
 
 	return true, ""
@@ -87,6 +103,12 @@ func ValidateEmail(correo string) (bool, string) {
 
 // ValidatePhone valida que el teléfono tenga una lada permitida
 func ValidatePhone(telefono string) (bool, string) {
+	return ValidatePhoneAreaCodes(telefono, DefaultPhoneAreaCodes, DefaultRegion)
+}
+
+// ValidatePhoneAreaCodes valida que el teléfono tenga una de las ladas
+// indicadas; region solo se usa en el mensaje de error
+func ValidatePhoneAreaCodes(telefono string, allowedAreaCodes []string, region string) (bool, string) {
 	telefono = strings.TrimSpace(telefono)
 
 	if telefono == "" {
@@ -106,13 +128,6 @@ func ValidatePhone(telefono string) (bool, string) {
 		return false, "El teléfono debe tener al menos 10 dígitos"
 	}
 
-	// Ladas permitidas de Chiapas
-	allowedAreaCodes := []string{
-		"916", "917", "918", "919", "932", "934",
-		"961", "962", "963", "964", "965", "966",
-		"967", "968", "992", "994",
-	}
-
 	// Verificar lada (primeros 3 dígitos)
 	if len(cleanPhone) >= 3 {
 		areaCode := cleanPhone[:3]
@@ -125,7 +140,7 @@ func ValidatePhone(telefono string) (bool, string) {
 		}
 
 		if !validAreaCode {
-			return false, "La lada del teléfono no es válida para Chiapas. Ladas permitidas: " + strings.Join(allowedAreaCodes, ", ")
+			return false, "La lada del teléfono no es válida para " + region + ". Ladas permitidas: " + strings.Join(allowedAreaCodes, ", ")
 		}
 	}
 
@@ -148,3 +163,20 @@ func CleanString(s string) string {
 func IsEmpty(s string) bool {
 	return strings.TrimSpace(s) == ""
 }
+
+// NormalizeEmail normaliza un correo para compararlo: sin espacios y en minúsculas
+func NormalizeEmail(correo string) string {
+	return strings.ToLower(strings.TrimSpace(correo))
+}
+
+// NormalizePhone normaliza un teléfono para compararlo conservando solo sus dígitos
+func NormalizePhone(telefono string) string {
+	var b strings.Builder
+	b.Grow(len(telefono))
+	for _, r := range telefono {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
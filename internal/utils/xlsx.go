@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxXLSXEntries entradas máximas del zip de un libro de Excel; un libro
+// normal tiene unas decenas
+const maxXLSXEntries = 10000
+
+var (
+	// ErrNotXLSX el contenido no es un libro de Excel (.xlsx)
+	ErrNotXLSX = errors.New("el contenido no es un libro de Excel (.xlsx)")
+	// ErrXLSXTooLarge el libro ocupa descomprimido más de lo permitido
+	ErrXLSXTooLarge = errors.New("el libro de Excel descomprimido excede el tamaño máximo permitido")
+)
+
+// zipMagic firma de un archivo zip con entradas locales
+var zipMagic = []byte("PK\x03\x04")
+
+// VerifyXLSX comprueba que el contenido sea un libro de Excel OOXML: un zip
+// con [Content_Types].xml que declare un libro de hojas de cálculo y con
+// xl/workbook.xml. Para proteger contra bombas zip descomprime todas las
+// entradas y falla con ErrXLSXTooLarge si entre todas superan
+// maxUncompressed bytes; no se confía en los tamaños que declara el zip.
+func VerifyXLSX(r io.ReaderAt, size, maxUncompressed int64) error {
+	magic := make([]byte, len(zipMagic))
+	if _, err := r.ReadAt(magic, 0); err != nil || !bytes.Equal(magic, zipMagic) {
+		return ErrNotXLSX
+	}
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotXLSX, err)
+	}
+	if len(archive.File) > maxXLSXEntries {
+		return fmt.Errorf("%w: contiene %d entradas", ErrXLSXTooLarge, len(archive.File))
+	}
+
+	var contentTypes *zip.File
+	hasWorkbook := false
+	var declared uint64
+	for _, entry := range archive.File {
+		switch entry.Name {
+		case "[Content_Types].xml":
+			contentTypes = entry
+		case "xl/workbook.xml":
+			hasWorkbook = true
+		}
+		declared += entry.UncompressedSize64
+	}
+
+	if contentTypes == nil || !hasWorkbook {
+		return fmt.Errorf("%w: faltan las partes de un libro OOXML", ErrNotXLSX)
+	}
+	if declared > uint64(maxUncompressed) {
+		return ErrXLSXTooLarge
+	}
+
+	// Descomprimir todo, contando los bytes reales
+	remaining := maxUncompressed
+	for _, entry := range archive.File {
+		n, err := inflateEntry(entry, remaining)
+		if err != nil {
+			return fmt.Errorf("%w: entrada %s dañada: %v", ErrNotXLSX, entry.Name, err)
+		}
+		remaining -= n
+		if remaining < 0 {
+			return ErrXLSXTooLarge
+		}
+	}
+
+	types, err := readEntry(contentTypes, 1<<20)
+	if err != nil || !strings.Contains(string(types), "spreadsheetml.sheet.main+xml") {
+		return fmt.Errorf("%w: no declara un libro de hojas de cálculo", ErrNotXLSX)
+	}
+
+	return nil
+}
+
+// inflateEntry descomprime una entrada descartando su contenido. Lee como
+// máximo limit+1 bytes y devuelve cuántos leyó. Descomprime los datos en
+// bruto porque el lector de archive/zip corta en el tamaño declarado con
+// ErrFormat, y una entrada que declara menos de lo que ocupa debe contar
+// para el límite con su tamaño real.
+func inflateEntry(entry *zip.File, limit int64) (int64, error) {
+	raw, err := entry.OpenRaw()
+	if err != nil {
+		return 0, err
+	}
+
+	var data io.Reader
+	switch entry.Method {
+	case zip.Store:
+		data = raw
+	case zip.Deflate:
+		inflater := flate.NewReader(raw)
+		defer inflater.Close()
+		data = inflater
+	default:
+		return 0, zip.ErrAlgorithm
+	}
+
+	n, err := io.Copy(io.Discard, io.LimitReader(data, limit+1))
+	if err == nil && n <= limit && uint64(n) != entry.UncompressedSize64 {
+		return n, fmt.Errorf("%w: ocupa %d bytes y declara %d", zip.ErrFormat, n, entry.UncompressedSize64)
+	}
+	return n, err
+}
+
+// readEntry lee una entrada pequeña completa, hasta limit bytes
+func readEntry(entry *zip.File, limit int64) ([]byte, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(io.LimitReader(rc, limit))
+}
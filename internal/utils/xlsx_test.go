@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const spreadsheetContentTypes = `<?xml version="1.0" encoding="UTF-8"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+</Types>`
+
+// zipEntry entrada de un zip de prueba. Si declared no es cero, el zip
+// declara ese tamaño descomprimido en lugar del real.
+type zipEntry struct {
+	name     string
+	content  string
+	declared uint64
+}
+
+func buildZip(t *testing.T, entries ...zipEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		var compressed bytes.Buffer
+		deflater, err := flate.NewWriter(&compressed, flate.BestCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deflater.Write([]byte(entry.content))
+		if err := deflater.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		header := &zip.FileHeader{
+			Name:               entry.name,
+			Method:             zip.Deflate,
+			CRC32:              crc32.ChecksumIEEE([]byte(entry.content)),
+			CompressedSize64:   uint64(compressed.Len()),
+			UncompressedSize64: uint64(len(entry.content)),
+		}
+		if entry.declared != 0 {
+			header.UncompressedSize64 = entry.declared
+		}
+
+		part, err := w.CreateRaw(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(compressed.Bytes())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func validWorkbook(t *testing.T) []byte {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetSheetRow("Sheet1", "A1", &[]string{"Clave", "Nombre", "Correo", "Telefono"})
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestVerifyXLSX(t *testing.T) {
+	const maxUncompressed = 64 << 10
+
+	contentTypes := zipEntry{name: "[Content_Types].xml", content: spreadsheetContentTypes}
+	workbook := zipEntry{name: "xl/workbook.xml", content: "<workbook/>"}
+	bomb := strings.Repeat("0", 4*maxUncompressed)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "libro válido", data: validWorkbook(t)},
+		{name: "libro mínimo", data: buildZip(t, contentTypes, workbook)},
+		{name: "vacío", data: nil, wantErr: ErrNotXLSX},
+		{name: "CSV", data: []byte("Clave,Nombre,Correo,Telefono\n1,Ana,ana@gmail.com,9621234567\n"), wantErr: ErrNotXLSX},
+		{name: "firma zip sin zip", data: append([]byte("PK\x03\x04"), make([]byte, 64)...), wantErr: ErrNotXLSX},
+		{name: "sin [Content_Types].xml", data: buildZip(t, workbook), wantErr: ErrNotXLSX},
+		{name: "sin xl/workbook.xml", data: buildZip(t, contentTypes, zipEntry{name: "word/document.xml", content: "<document/>"}), wantErr: ErrNotXLSX},
+		{
+			name:    "no declara un libro de hojas de cálculo",
+			data:    buildZip(t, zipEntry{name: "[Content_Types].xml", content: "<Types/>"}, workbook),
+			wantErr: ErrNotXLSX,
+		},
+		{
+			name:    "tamaño declarado excesivo",
+			data:    buildZip(t, contentTypes, workbook, zipEntry{name: "xl/worksheets/sheet1.xml", content: bomb}),
+			wantErr: ErrXLSXTooLarge,
+		},
+		{
+			name:    "declara poco y descomprime más del máximo",
+			data:    buildZip(t, contentTypes, workbook, zipEntry{name: "xl/worksheets/sheet1.xml", content: bomb, declared: 100}),
+			wantErr: ErrXLSXTooLarge,
+		},
+		{
+			name: "entre todas las entradas descomprimen más del máximo",
+			data: buildZip(t, contentTypes, workbook,
+				zipEntry{name: "xl/a.xml", content: bomb[:maxUncompressed*3/4]},
+				zipEntry{name: "xl/b.xml", content: bomb[:maxUncompressed*3/4], declared: 100}),
+			wantErr: ErrXLSXTooLarge,
+		},
+		{
+			name:    "declara menos de lo que ocupa sin llegar al máximo",
+			data:    buildZip(t, contentTypes, workbook, zipEntry{name: "xl/worksheets/sheet1.xml", content: bomb[:1000], declared: 100}),
+			wantErr: ErrNotXLSX,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyXLSX(bytes.NewReader(tt.data), int64(len(tt.data)), maxUncompressed)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("VerifyXLSX: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyXLSX: %v, se esperaba %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package response
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -20,6 +22,8 @@ type APIResponse struct {
 type ErrorInfo struct {
 	Code    string `json:"code,omitempty"`
 	Details string `json:"details,omitempty"`
+	// RetryAfter segundos que conviene esperar antes de reintentar
+	RetryAfter int `json:"retry_after,omitempty"`
 }
 
 // Success devuelve una respuesta exitosa
@@ -91,6 +95,18 @@ func Created(c *gin.Context, message string, data interface{}) {
 	c.JSON(http.StatusCreated, response)
 }
 
+// Accepted devuelve una respuesta de petición aceptada para procesamiento asíncrono
+func Accepted(c *gin.Context, message string, data interface{}) {
+	response := APIResponse{
+		Success:   true,
+		Message:   message,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
 // NoContent devuelve una respuesta sin contenido
 func NoContent(c *gin.Context) {
 	c.Status(http.StatusNoContent)
@@ -134,6 +150,43 @@ func Forbidden(c *gin.Context, message string) {
 	c.JSON(http.StatusForbidden, response)
 }
 
+// ForbiddenWithCode devuelve una respuesta de prohibido con código específico
+func ForbiddenWithCode(c *gin.Context, code, message string) {
+	ErrorWithCode(c, http.StatusForbidden, code, message)
+}
+
+// TooManyRequests devuelve una respuesta de demasiadas peticiones. retryAfter
+// se indica en la cabecera Retry-After y en el cuerpo, redondeado a segundos.
+func TooManyRequests(c *gin.Context, code, message string, retryAfter time.Duration) {
+	if message == "" {
+		message = "Demasiadas peticiones"
+	}
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+
+	response := APIResponse{
+		Success: false,
+		Message: message,
+		Error: &ErrorInfo{
+			Code:       code,
+			Details:    message,
+			RetryAfter: seconds,
+		},
+		Timestamp: time.Now(),
+	}
+
+	c.JSON(http.StatusTooManyRequests, response)
+}
+
+// PayloadTooLarge devuelve una respuesta de contenido demasiado grande
+func PayloadTooLarge(c *gin.Context, code, message string) {
+	ErrorWithCode(c, http.StatusRequestEntityTooLarge, code, message)
+}
+
 // NotFound devuelve una respuesta de no encontrado
 func NotFound(c *gin.Context, message string) {
 	if message == "" {